@@ -0,0 +1,319 @@
+package dbms
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/Qhodok/go-tools/list"
+	"github.com/jinzhu/gorm"
+	"sync"
+	"time"
+)
+
+// ListStorage keeps the elements of a list in a key/value table so a
+// go_tools.List (or list.List[string, V]) can be backed by the database:
+//
+//	storage, err := dbms.NewListStorage(repository, "sessions")
+//	sessions := go_tools.NewPointerListWithStorage(storage)
+//
+// Add and Update are both upserts. A new key is given the next position, an
+// existing key keeps its position, Move repositions one key and Reorder
+// rewrites all of them. Writes that touch positions lock them for their
+// transaction, so concurrent inserts never share a position. On MySQL that is
+// a locking read of the table, which covers inserts under the default
+// REPEATABLE READ isolation.
+type ListStorage struct {
+	repository *DatabaseRepository
+	table      string
+	locker     sync.Mutex
+	err        error
+}
+
+// NewListStorage creates the table when it does not exist yet. repository has
+// to be connected already.
+func NewListStorage(repository *DatabaseRepository, table string) (*ListStorage, error) {
+	if !repository.status {
+		return nil, fmt.Errorf("dbms : not connected")
+	}
+	this := &ListStorage{repository: repository, table: table}
+	payload := "BYTEA"
+	if repository.Database.Dialect().GetName() == "mysql" {
+		payload = "LONGBLOB"
+	}
+	err := this.exec("CREATE TABLE IF NOT EXISTS " + this.quote(table) + " (" +
+		this.quote("key") + " VARCHAR(255) NOT NULL PRIMARY KEY, " +
+		this.quote("payload") + " " + payload + ", " +
+		this.quote("updated_at") + " TIMESTAMP NOT NULL, " +
+		this.quote("position") + " BIGINT NOT NULL)")
+	if err != nil {
+		return nil, err
+	}
+	return this, nil
+}
+
+func (this *ListStorage) Add(key string, data []byte) {
+	this.setErr(this.upsert(key, data))
+}
+
+func (this *ListStorage) Update(key string, data []byte) {
+	this.setErr(this.upsert(key, data))
+}
+
+func (this *ListStorage) Delete(key string) {
+	this.setErr(this.remove(key))
+}
+
+// Batch applies writes in one transaction, for list.Batch and write-behind
+// flushes.
+func (this *ListStorage) Batch(writes []list.StorageWrite[string]) error {
+	now := time.Now()
+	return this.transaction(func(tx *gorm.DB) error {
+		for _, write := range writes {
+			var err error
+			if write.Op == "delete" {
+				err = tx.Exec(this.removeStatement(), write.Key).Error
+			} else {
+				err = this.upsertTx(tx, write.Key, write.Data, now)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Get returns the payload as []byte, nil when the key is not stored.
+func (this *ListStorage) Get(key string) interface{} {
+	payload, err := this.get(key)
+	this.setErr(err)
+	if payload == nil {
+		return nil
+	}
+	return payload
+}
+
+// Checked returns a view of the storage whose methods report their errors,
+// for list.NewWithCheckedStorage.
+func (this *ListStorage) Checked() *CheckedListStorage {
+	return &CheckedListStorage{this}
+}
+
+// Iterate calls fn with every key and payload ordered by position, until fn
+// returns false. The rows are read before fn is called.
+func (this *ListStorage) Iterate(fn func(key string, data interface{}) bool) error {
+	if !this.repository.status {
+		return fmt.Errorf("dbms : not connected")
+	}
+	var keys []string
+	var payloads [][]byte
+	this.repository.Begin()
+	rows, err := this.repository.Database.Raw("SELECT " + this.quote("key") + ", " + this.quote("payload") +
+		" FROM " + this.quote(this.table) + " ORDER BY " + this.quote("position")).Rows()
+	if err == nil {
+		for rows.Next() {
+			var key string
+			var payload []byte
+			if err = rows.Scan(&key, &payload); err != nil {
+				break
+			}
+			keys = append(keys, key)
+			payloads = append(payloads, payload)
+		}
+		if err == nil {
+			err = rows.Err()
+		}
+		rows.Close()
+	}
+	this.repository.End()
+	if err != nil {
+		return err
+	}
+	for index, key := range keys {
+		if !fn(key, payloads[index]) {
+			break
+		}
+	}
+	return nil
+}
+
+// Reorder stores keys, head first, as the new positions.
+func (this *ListStorage) Reorder(keys []string) {
+	statement := "UPDATE " + this.quote(this.table) + " SET " + this.quote("position") + " = ? WHERE " + this.quote("key") + " = ?"
+	this.setErr(this.transaction(func(tx *gorm.DB) error {
+		for position, key := range keys {
+			if err := tx.Exec(statement, position+1, key).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+// Move gives key the position right behind after, or in front of every other
+// key when first is true. It takes one transaction of four statements at
+// most, the lock on the positions included, whatever the number of keys.
+func (this *ListStorage) Move(key string, after string, first bool) error {
+	table := this.quote(this.table)
+	position := this.quote("position")
+	return this.transaction(func(tx *gorm.DB) error {
+		var target int64
+		var err error
+		if first {
+			err = tx.Raw("SELECT COALESCE(MIN(" + position + "), 0) FROM " + table).Row().Scan(&target)
+			target--
+		} else {
+			err = tx.Raw("SELECT "+position+" FROM "+table+" WHERE "+this.quote("key")+" = ?", after).Row().Scan(&target)
+			if err == nil {
+				err = tx.Exec("UPDATE "+table+" SET "+position+" = "+position+" + 1 WHERE "+position+" > ?", target).Error
+			}
+			target++
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Exec("UPDATE "+table+" SET "+position+" = ? WHERE "+this.quote("key")+" = ?", target, key).Error
+	})
+}
+
+// transaction runs fn in one database transaction with the positions locked,
+// it commits when fn returns nil and rolls back otherwise.
+func (this *ListStorage) transaction(fn func(tx *gorm.DB) error) error {
+	if !this.repository.status {
+		return fmt.Errorf("dbms : not connected")
+	}
+	this.repository.Begin()
+	defer this.repository.End()
+	tx := this.repository.Database.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	err := this.lockPositions(tx)
+	if err == nil {
+		err = fn(tx)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// lockPositions keeps other writers of the table waiting until tx ends, reads
+// go on.
+func (this *ListStorage) lockPositions(tx *gorm.DB) error {
+	table := this.quote(this.table)
+	if tx.Dialect().GetName() == "mysql" {
+		var count int64
+		return tx.Raw("SELECT COUNT(*) FROM " + table + " FOR UPDATE").Row().Scan(&count)
+	}
+	return tx.Exec("LOCK TABLE " + table + " IN SHARE ROW EXCLUSIVE MODE").Error
+}
+
+// Err returns the last error of a write, Storage has no way to report it.
+func (this *ListStorage) Err() error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return this.err
+}
+
+func (this *ListStorage) get(key string) ([]byte, error) {
+	if !this.repository.status {
+		return nil, fmt.Errorf("dbms : not connected")
+	}
+	var payload []byte
+	this.repository.Begin()
+	err := this.repository.Database.Raw("SELECT "+this.quote("payload")+" FROM "+this.quote(this.table)+
+		" WHERE "+this.quote("key")+" = ?", key).Row().Scan(&payload)
+	this.repository.End()
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func (this *ListStorage) remove(key string) error {
+	return this.exec(this.removeStatement(), key)
+}
+
+func (this *ListStorage) upsert(key string, data []byte) error {
+	now := time.Now()
+	return this.transaction(func(tx *gorm.DB) error {
+		return this.upsertTx(tx, key, data, now)
+	})
+}
+
+// upsertTx updates key where it is or inserts it behind every other key, tx
+// has to hold the lock on the positions.
+func (this *ListStorage) upsertTx(tx *gorm.DB, key string, data []byte, now time.Time) error {
+	table := this.quote(this.table)
+	position := this.quote("position")
+	var current int64
+	err := tx.Raw("SELECT "+position+" FROM "+table+" WHERE "+this.quote("key")+" = ?", key).Row().Scan(&current)
+	if err == nil {
+		return tx.Exec("UPDATE "+table+" SET "+this.quote("payload")+" = ?, "+this.quote("updated_at")+" = ? WHERE "+
+			this.quote("key")+" = ?", data, now, key).Error
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+	if err = tx.Raw("SELECT COALESCE(MAX(" + position + "), 0) + 1 FROM " + table).Row().Scan(&current); err != nil {
+		return err
+	}
+	return tx.Exec("INSERT INTO "+table+" ("+this.quote("key")+", "+this.quote("payload")+", "+this.quote("updated_at")+
+		", "+position+") VALUES (?, ?, ?, ?)", key, data, now, current).Error
+}
+
+func (this *ListStorage) removeStatement() string {
+	return "DELETE FROM " + this.quote(this.table) + " WHERE " + this.quote("key") + " = ?"
+}
+
+func (this *ListStorage) exec(statement string, value ...interface{}) (err error) {
+	if this.repository.status {
+		this.repository.Begin()
+		err = this.repository.Database.Exec(statement, value...).Error
+		this.repository.End()
+	} else {
+		err = fmt.Errorf("dbms : not connected")
+	}
+	return
+}
+
+func (this *ListStorage) quote(name string) string {
+	return this.repository.Database.Dialect().Quote(name)
+}
+
+func (this *ListStorage) setErr(err error) {
+	if err != nil {
+		this.locker.Lock()
+		this.err = err
+		this.locker.Unlock()
+	}
+}
+
+// CheckedListStorage is the ListStorage with error returns, Reorder still only
+// records its error in Err.
+type CheckedListStorage struct {
+	*ListStorage
+}
+
+func (this *CheckedListStorage) Add(key string, data []byte) error {
+	return this.upsert(key, data)
+}
+
+func (this *CheckedListStorage) Update(key string, data []byte) error {
+	return this.upsert(key, data)
+}
+
+func (this *CheckedListStorage) Delete(key string) error {
+	return this.remove(key)
+}
+
+func (this *CheckedListStorage) Get(key string) (interface{}, error) {
+	payload, err := this.get(key)
+	if payload == nil {
+		return nil, err
+	}
+	return payload, nil
+}
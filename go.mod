@@ -7,6 +7,7 @@ require (
 	github.com/jinzhu/gorm v1.9.16
 	github.com/labstack/gommon v0.4.1
 	github.com/mattn/go-isatty v0.0.20
+	github.com/pkg/errors v0.9.1
 	github.com/spf13/viper v1.17.0
 	github.com/valyala/fasttemplate v1.2.2
 	go.uber.org/zap v1.26.0
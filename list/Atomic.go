@@ -0,0 +1,99 @@
+package list
+
+import (
+	"github.com/pkg/errors"
+	"reflect"
+)
+
+// CompareAndSwap replaces the value of key with new only while it still equals
+// old. Values are compared with == when their type allows it, otherwise with
+// reflect.DeepEqual. Like AddLastOrUpdate it writes storage and broadcasts
+// UPDATE.
+func (this *List[K, V]) CompareAndSwap(key K, old V, new V) (bool, error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	data, ok := this.container[key]
+	if !ok {
+		return false, errors.New("data not found")
+	}
+	if !sameValue(data.Data, old) {
+		return false, nil
+	}
+	if err := this.replaceNoLock(data, new); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UpdateFunc replaces the value of key with what fn returns for the current
+// one, nothing changes when fn returns false. fn runs with the list locked and
+// must not call back into it.
+func (this *List[K, V]) UpdateFunc(key K, fn func(old V) (V, bool)) (bool, error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	data, ok := this.container[key]
+	if !ok {
+		return false, errors.New("data not found")
+	}
+	value, change := fn(data.Data)
+	if !change {
+		return false, nil
+	}
+	if err := this.replaceNoLock(data, value); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetOrAdd returns the element of key, reading through to storage like Find,
+// and appends what factory makes when there is none. loaded tells which one
+// happened. factory runs with the list locked and must not call back into it.
+func (this *List[K, V]) GetOrAdd(key K, factory func() V) (element V, loaded bool, err error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if element, loaded = this.findNoLock(key); loaded {
+		return
+	}
+	element = factory()
+	if _, err = this.addLastNoLock(key, element); err != nil {
+		var zero V
+		return zero, false, err
+	}
+	return
+}
+
+// replaceNoLock writes value to storage and then into data.
+func (this *List[K, V]) replaceNoLock(data *Component[K, V], value V) error {
+	payload, err := this.encodeNoLock(value)
+	if err != nil {
+		return err
+	}
+	if err := this.storageUpdate(data.Key, payload); err != nil {
+		return err
+	}
+	old := data.Data
+	data.Data = value
+	this.indexNoLock(data)
+	this.broadcastUpdateNoLock(data, old)
+	this.touchNoLock(data)
+	return nil
+}
+
+// sameValue compares with == when that cannot panic, a struct or array with
+// an interface field holding a slice or map passes Comparable but makes ==
+// panic, so that case falls back to reflect.DeepEqual.
+func sameValue[V any](left V, right V) (same bool) {
+	a, b := any(left), any(right)
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if !reflect.TypeOf(a).Comparable() || !reflect.TypeOf(b).Comparable() {
+		return reflect.DeepEqual(a, b)
+	}
+	defer func() {
+		if recover() != nil {
+			same = reflect.DeepEqual(a, b)
+		}
+	}()
+	return a == b
+}
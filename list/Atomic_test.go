@@ -0,0 +1,19 @@
+package list
+
+import "testing"
+
+func TestCompareAndSwapUncomparable(t *testing.T) {
+	type holder struct {
+		X interface{}
+	}
+	list := New[string, interface{}]()
+	list.AddLast("a", holder{X: []int{1}})
+	swapped, err := list.CompareAndSwap("a", holder{X: []int{1}}, 5)
+	if err != nil || !swapped || list.Find("a") != 5 {
+		t.Fatal(swapped, err, list.Find("a"))
+	}
+	swapped, err = list.CompareAndSwap("a", holder{X: []int{2}}, 6)
+	if err != nil || swapped || list.Find("a") != 5 {
+		t.Fatal(swapped, err, list.Find("a"))
+	}
+}
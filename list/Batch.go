@@ -0,0 +1,307 @@
+package list
+
+import (
+	"github.com/pkg/errors"
+	"time"
+)
+
+// ListTx is the view of a list inside Batch. Its changes are visible to the
+// transaction right away but reach subscribers and storage only when the
+// batch commits. It must not be used after Batch returns.
+type ListTx[K comparable, V any] struct {
+	list   *List[K, V]
+	undo   []func()
+	events []Event[K, V]
+	writes writeQueue[K]
+	before map[K]txBefore[V]
+	closed bool
+}
+
+// txBefore is what the list held for a key before the batch touched it.
+type txBefore[V any] struct {
+	exist bool
+	data  V
+}
+
+// Batch runs fn with the list locked and applies its changes all or nothing.
+// When fn returns an error or panics, or storage refuses the writes, every
+// change is undone and nothing is broadcast. Otherwise one BATCH event holds
+// the changes and the storage writes, merged per key, are sent together: in
+// one call to a BatchStorage, else one by one. In the second case a failed
+// write makes Batch put the writes that were already sent back to the old
+// values, failures of that only reach the storage error handler.
+//
+// An LRU list evicts after the commit, the batch itself may grow it.
+func (this *List[K, V]) Batch(fn func(tx *ListTx[K, V]) error) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	tx, err := this.commitNoLock(fn)
+	if err != nil || len(tx.events) == 0 {
+		return err
+	}
+	this.sequence++
+	event := Event[K, V]{
+		Component: tx.events[0].Component,
+		Event:     BATCH,
+		Sequence:  this.sequence,
+		Time:      time.Now(),
+		Order:     this.keysNoLock(),
+		Batch:     tx.events,
+	}
+	for index := range event.Batch {
+		event.Batch[index].Sequence = this.sequence
+	}
+	this.broadcastEvent(event)
+	this.evictNoLock(nil)
+	return nil
+}
+
+// commitNoLock runs fn and sends its writes the way Batch describes, the
+// caller broadcasts tx.events once it returns without an error.
+func (this *List[K, V]) commitNoLock(fn func(tx *ListTx[K, V]) error) (*ListTx[K, V], error) {
+	tx := &ListTx[K, V]{list: this, before: make(map[K]txBefore[V])}
+	committed := false
+	defer func() {
+		tx.closed = true
+		if !committed {
+			tx.rollbackNoLock()
+		}
+	}()
+	if err := fn(tx); err != nil {
+		return nil, err
+	}
+	if len(tx.events) > 0 {
+		if err := this.batchWriteNoLock(tx); err != nil {
+			return nil, err
+		}
+	}
+	committed = true
+	return tx, nil
+}
+
+func (this *ListTx[K, V]) Find(target K) (element V, ok bool) {
+	if this.closed {
+		return
+	}
+	data, ok := this.list.container[target]
+	if ok {
+		element = data.Data
+	}
+	return
+}
+
+func (this *ListTx[K, V]) AddLast(key K, data V) error {
+	temp, err := this.insert(key, data)
+	if err != nil {
+		return err
+	}
+	this.list.linkRecentNoLock(temp)
+	this.added(temp)
+	return nil
+}
+
+func (this *ListTx[K, V]) AddFirst(key K, data V) error {
+	temp, err := this.insert(key, data)
+	if err != nil {
+		return err
+	}
+	this.list.linkFirstNoLock(temp)
+	this.added(temp)
+	return nil
+}
+
+func (this *ListTx[K, V]) AddAfter(key K, data V, target K) (bool, error) {
+	if this.closed {
+		return false, errors.New("transaction is closed")
+	}
+	other, ok := this.list.container[target]
+	if !ok {
+		return false, errors.New("target not found")
+	}
+	temp, err := this.insert(key, data)
+	if err != nil {
+		return false, err
+	}
+	this.list.linkAfterNoLock(temp, other)
+	this.added(temp)
+	return true, nil
+}
+
+func (this *ListTx[K, V]) AddBefore(key K, data V, target K) (bool, error) {
+	if this.closed {
+		return false, errors.New("transaction is closed")
+	}
+	other, ok := this.list.container[target]
+	if !ok {
+		return false, errors.New("target not found")
+	}
+	temp, err := this.insert(key, data)
+	if err != nil {
+		return false, err
+	}
+	this.list.linkBeforeNoLock(temp, other)
+	this.added(temp)
+	return true, nil
+}
+
+// Remove takes target out of the list, unlike List.Remove a key that is not
+// in the list is not deleted from storage.
+func (this *ListTx[K, V]) Remove(target K) (element V) {
+	if this.closed {
+		return
+	}
+	data, ok := this.list.container[target]
+	if !ok {
+		return
+	}
+	this.remember(data)
+	prev := data.Prev
+	expiry, expires := this.list.expires[target]
+	this.events = append(this.events, eventOf(DELETE, data))
+	this.list.unlinkNoLock(data)
+	this.writes.put(StorageWrite[K]{Op: "delete", Key: target})
+	this.undo = append(this.undo, func() {
+		if prev == nil {
+			this.list.linkFirstNoLock(data)
+		} else {
+			this.list.linkAfterNoLock(data, prev)
+		}
+		if expires {
+			this.list.setDeadlineNoLock(target, expiry.at)
+		}
+	})
+	return data.Data
+}
+
+func (this *ListTx[K, V]) Update(key K, data V) error {
+	if this.closed {
+		return errors.New("transaction is closed")
+	}
+	val, ok := this.list.container[key]
+	if !ok {
+		return errors.New("data not found")
+	}
+	payload, err := this.list.encodeNoLock(data)
+	if err != nil {
+		return err
+	}
+	this.remember(val)
+	old := val.Data
+	val.Data = data
+	this.list.indexNoLock(val)
+	event := eventOf(UPDATE, val)
+	event.Old = old
+	this.events = append(this.events, event)
+	this.writes.put(StorageWrite[K]{Op: "update", Key: key, Data: payload})
+	this.undo = append(this.undo, func() {
+		val.Data = old
+		this.list.indexNoLock(val)
+	})
+	return nil
+}
+
+// insert checks and encodes a new element, the caller links it.
+func (this *ListTx[K, V]) insert(key K, data V) (*Component[K, V], error) {
+	if this.closed {
+		return nil, errors.New("transaction is closed")
+	}
+	if _, ok := this.list.container[key]; ok {
+		return nil, errors.New("duplicate key")
+	}
+	payload, err := this.list.encodeNoLock(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := this.before[key]; !ok {
+		this.before[key] = txBefore[V]{}
+	}
+	this.writes.put(StorageWrite[K]{Op: "add", Key: key, Data: payload})
+	return &Component[K, V]{Data: data, Key: key}, nil
+}
+
+func (this *ListTx[K, V]) added(temp *Component[K, V]) {
+	this.events = append(this.events, eventOf(ADD, temp))
+	this.undo = append(this.undo, func() {
+		this.list.unlinkNoLock(temp)
+	})
+}
+
+// remember keeps the value data had before its first change in the batch.
+func (this *ListTx[K, V]) remember(data *Component[K, V]) {
+	if _, ok := this.before[data.Key]; !ok {
+		this.before[data.Key] = txBefore[V]{exist: true, data: data.Data}
+	}
+}
+
+func (this *ListTx[K, V]) rollbackNoLock() {
+	for index := len(this.undo) - 1; index >= 0; index-- {
+		this.undo[index]()
+	}
+	this.undo = nil
+}
+
+// batchWriteNoLock sends the writes of a committing batch, see Batch.
+func (this *List[K, V]) batchWriteNoLock(tx *ListTx[K, V]) error {
+	writes := tx.writes.list()
+	if this.storage == nil || len(writes) == 0 {
+		return nil
+	}
+	queued := this.writeBehind
+	for _, write := range writes {
+		this.forgetNoLock(write.Key)
+		if _, pending := this.unflushedNoLock(write.Key); pending {
+			queued = true
+		}
+	}
+	batcher, ok := batchStorage(this.storage)
+	if queued || !ok {
+		return this.sendEachNoLock(tx, writes)
+	}
+
+	err := this.retryNoLock(func() error {
+		return batcher.Batch(writes)
+	})
+	if err == nil {
+		return nil
+	}
+	failure := &StorageError[K]{Op: "batch", Err: err}
+	this.reportNoLock(failure)
+	if this.failure == QUEUE {
+		for _, write := range writes {
+			this.repairs.put(write)
+		}
+		return nil
+	}
+	return failure
+}
+
+// sendEachNoLock hands the writes to writeNoLock one by one. When one fails
+// the ones before it are reverted in storage.
+func (this *List[K, V]) sendEachNoLock(tx *ListTx[K, V], writes []StorageWrite[K]) error {
+	for index, write := range writes {
+		err := this.writeNoLock(write)
+		if err == nil {
+			continue
+		}
+		for _, sent := range writes[:index] {
+			revert := StorageWrite[K]{Op: "delete", Key: sent.Key}
+			if before := tx.before[sent.Key]; before.exist {
+				revert.Op = "update"
+				if sent.Op == "delete" {
+					revert.Op = "add"
+				}
+				payload, encodeErr := this.encodeNoLock(before.data)
+				if encodeErr != nil {
+					this.reportNoLock(&StorageError[K]{Op: revert.Op, Key: sent.Key, Err: encodeErr})
+					continue
+				}
+				revert.Data = payload
+			}
+			if revertErr := this.sendNoLock(revert); revertErr != nil {
+				this.reportNoLock(&StorageError[K]{Op: revert.Op, Key: sent.Key, Err: revertErr})
+			}
+		}
+		return err
+	}
+	return nil
+}
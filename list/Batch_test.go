@@ -0,0 +1,172 @@
+package list
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// batchStore is a testStorage that is also a BatchStorage.
+type batchStore struct {
+	*testStorage
+	batches [][]StorageWrite[string]
+}
+
+func (this *batchStore) Batch(writes []StorageWrite[string]) error {
+	this.batches = append(this.batches, writes)
+	for _, write := range writes {
+		if err := sendWrite[string](this.testStorage, write); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newStoredABCD(storage CheckedStorage[string]) *List[string, int] {
+	list := NewWithCheckedStorage[string, int](storage)
+	for index, key := range []string{"a", "b", "c", "d"} {
+		list.AddLast(key, index)
+	}
+	return list
+}
+
+// mixedBatch adds, updates, removes and moves d to the front, a move inside a
+// batch is a Remove followed by an add.
+func mixedBatch(tx *ListTx[string, int]) error {
+	if err := tx.AddFirst("x", 10); err != nil {
+		return err
+	}
+	if err := tx.Update("b", 11); err != nil {
+		return err
+	}
+	tx.Remove("c")
+	tx.Remove("d")
+	if err := tx.AddFirst("d", 13); err != nil {
+		return err
+	}
+	if _, err := tx.AddAfter("y", 14, "a"); err != nil {
+		return err
+	}
+	if value, ok := tx.Find("b"); !ok || value != 11 {
+		return errors.New("the batch does not see its own update")
+	}
+	return nil
+}
+
+func storedOf(storage *testStorage) string {
+	storage.locker.Lock()
+	defer storage.locker.Unlock()
+	return fmt.Sprint(storage.data)
+}
+
+func TestBatchRollback(t *testing.T) {
+	storage := newTestStorage()
+	list := newStoredABCD(storage)
+	subscription := list.Subscribe(1, nil)
+	defer subscription.Unsubscribe()
+	stored := storedOf(storage)
+	calls := storage.calls
+
+	failures := map[string]func(tx *ListTx[string, int]) error{
+		"error": func(tx *ListTx[string, int]) error {
+			mixedBatch(tx)
+			return errors.New("give up")
+		},
+		"panic": func(tx *ListTx[string, int]) error {
+			mixedBatch(tx)
+			panic("give up")
+		},
+	}
+	for name, fn := range failures {
+		func() {
+			defer func() {
+				recover()
+			}()
+			list.Batch(fn)
+		}()
+		if got := fmt.Sprint(list.Slice()); got != "[{a 0} {b 1} {c 2} {d 3}]" {
+			t.Fatal(name, got)
+		}
+		if list.IndexOf("d") != 3 || list.Find("x") != 0 {
+			t.Fatal(name, list.IndexOf("d"))
+		}
+	}
+	if storedOf(storage) != stored || storage.calls != calls || len(subscription.Events()) != 0 {
+		t.Fatal(storedOf(storage), storage.calls-calls, len(subscription.Events()))
+	}
+}
+
+func TestBatchEvent(t *testing.T) {
+	storage := &batchStore{testStorage: newTestStorage()}
+	list := newStoredABCD(storage)
+	subscription := list.Subscribe(1, nil)
+	defer subscription.Unsubscribe()
+	if err := list.Batch(mixedBatch); err != nil {
+		t.Fatal(err)
+	}
+	if got := orderOf(list); got != "[d x a y b]" {
+		t.Fatal(got)
+	}
+	event := <-subscription.Events()
+	if event.Event != BATCH || fmt.Sprint(event.Order) != "[d x a y b]" {
+		t.Fatal(event.Event, event.Order)
+	}
+	var changes []string
+	for _, change := range event.Batch {
+		if change.Sequence != event.Sequence {
+			t.Fatal(change.Sequence, event.Sequence)
+		}
+		changes = append(changes, fmt.Sprint(change.Event, " ", change.Key, "=", change.Data))
+	}
+	want := fmt.Sprint([]string{
+		fmt.Sprint(ADD, " x=10"), fmt.Sprint(UPDATE, " b=11"), fmt.Sprint(DELETE, " c=2"),
+		fmt.Sprint(DELETE, " d=3"), fmt.Sprint(ADD, " d=13"), fmt.Sprint(ADD, " y=14"),
+	})
+	if fmt.Sprint(changes) != want {
+		t.Fatal(changes)
+	}
+	if event.Batch[1].Old != 1 {
+		t.Fatal(event.Batch[1].Old)
+	}
+	// one storage call with the writes merged per key
+	if len(storage.batches) != 1 || len(storage.batches[0]) != 6 {
+		t.Fatal(storage.batches)
+	}
+	if got := storedOf(storage.testStorage); got != "map[a:[48] b:[49 49] d:[49 51] x:[49 48] y:[49 52]]" {
+		t.Fatal(got)
+	}
+	// nothing changed, nothing sent
+	if err := list.Batch(func(tx *ListTx[string, int]) error { return nil }); err != nil || len(storage.batches) != 1 {
+		t.Fatal(err, len(storage.batches))
+	}
+}
+
+func TestBatchRevertsSentWrites(t *testing.T) {
+	storage := newTestStorage()
+	list := newStoredABCD(storage)
+	stored := storedOf(storage)
+	var reported []string
+	list.SetStorageErrorHandler(func(err *StorageError[string]) {
+		reported = append(reported, err.Op+" "+err.Key)
+	})
+	// writes go update a, delete b, add z, update c, the add fails
+	storage.failAt = storage.calls + 3
+	err := list.Batch(func(tx *ListTx[string, int]) error {
+		tx.Update("a", 10)
+		tx.Remove("b")
+		tx.AddLast("z", 12)
+		return tx.Update("c", 13)
+	})
+	if err == nil {
+		t.Fatal("batch committed")
+	}
+	if got := fmt.Sprint(list.Slice()); got != "[{a 0} {b 1} {c 2} {d 3}]" {
+		t.Fatal(got)
+	}
+	if got := storedOf(storage); got != stored {
+		t.Fatal(got, stored)
+	}
+	if fmt.Sprint(reported) != "[add z]" {
+		t.Fatal(reported)
+	}
+}
@@ -0,0 +1,635 @@
+package list
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// Codec turns list values into the payload handed to Storage and back. The
+// list always passes pointers, Encode gets a *V and Decode a *V to fill in.
+type Codec interface {
+	Encode(value interface{}) ([]byte, error)
+	Decode(data []byte, value interface{}) error
+}
+
+// JSONCodec is the default codec. Values behind interface{} come back as
+// map[string]interface{}, []interface{}, float64 and so on.
+type JSONCodec struct{}
+
+func (JSONCodec) Encode(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (JSONCodec) Decode(data []byte, value interface{}) error {
+	return json.Unmarshal(data, value)
+}
+
+// GobCodec keeps the concrete type of values stored behind interface{}, those
+// types have to be registered with gob.Register.
+type GobCodec struct{}
+
+func (GobCodec) Encode(value interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(value); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (GobCodec) Decode(data []byte, value interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(value)
+}
+
+// BytesCodec hands []byte values to storage untouched, for lists that already
+// hold serialized payloads.
+type BytesCodec struct{}
+
+func (BytesCodec) Encode(value interface{}) ([]byte, error) {
+	switch data := value.(type) {
+	case []byte:
+		return data, nil
+	case *[]byte:
+		return *data, nil
+	case *interface{}:
+		if b, ok := (*data).([]byte); ok {
+			return b, nil
+		}
+	}
+	return nil, errors.Errorf("bytes codec cannot encode %T", value)
+}
+
+func (BytesCodec) Decode(data []byte, value interface{}) error {
+	switch target := value.(type) {
+	case *[]byte:
+		*target = data
+	case *interface{}:
+		*target = data
+	default:
+		return errors.Errorf("bytes codec cannot decode into %T", value)
+	}
+	return nil
+}
+
+// BinaryCodec writes the MessagePack format. It is smaller and faster to parse
+// than JSON and keeps integers and binary data apart from floats and strings.
+// Structs are written as maps keyed by their json name in field order, types
+// implementing encoding.BinaryMarshaler (time.Time for one) as binary.
+type BinaryCodec struct{}
+
+func (BinaryCodec) Encode(value interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := packValue(&buffer, reflect.ValueOf(value)); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (BinaryCodec) Decode(data []byte, value interface{}) error {
+	target := reflect.ValueOf(value)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return errors.New("binary codec needs a non nil pointer")
+	}
+	reader := bytes.NewReader(data)
+	decoded, err := unpackValue(reader)
+	if err != nil {
+		return err
+	}
+	if reader.Len() > 0 {
+		return errors.New("binary codec: trailing data")
+	}
+	return assignValue(target.Elem(), decoded)
+}
+
+var binaryMarshaler = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+var binaryUnmarshaler = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+
+func packValue(buffer *bytes.Buffer, value reflect.Value) error {
+	if !value.IsValid() {
+		buffer.WriteByte(0xc0)
+		return nil
+	}
+	if value.Type().Implements(binaryMarshaler) && !(value.Kind() == reflect.Ptr && value.IsNil()) {
+		data, err := value.Interface().(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		packBytes(buffer, data)
+		return nil
+	}
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			buffer.WriteByte(0xc0)
+			return nil
+		}
+		return packValue(buffer, value.Elem())
+	case reflect.Bool:
+		if value.Bool() {
+			buffer.WriteByte(0xc3)
+		} else {
+			buffer.WriteByte(0xc2)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		packInt(buffer, value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		packUint(buffer, value.Uint())
+	case reflect.Float32, reflect.Float64:
+		buffer.WriteByte(0xcb)
+		binary.Write(buffer, binary.BigEndian, math.Float64bits(value.Float()))
+	case reflect.String:
+		packString(buffer, value.String())
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			buffer.WriteByte(0xc0)
+			return nil
+		}
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, value.Len())
+			reflect.Copy(reflect.ValueOf(data), value)
+			packBytes(buffer, data)
+			return nil
+		}
+		packHeader(buffer, value.Len(), 0x90, 0xdc)
+		for i := 0; i < value.Len(); i++ {
+			if err := packValue(buffer, value.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if value.IsNil() {
+			buffer.WriteByte(0xc0)
+			return nil
+		}
+		packHeader(buffer, value.Len(), 0x80, 0xde)
+		iterator := value.MapRange()
+		for iterator.Next() {
+			if err := packValue(buffer, iterator.Key()); err != nil {
+				return err
+			}
+			if err := packValue(buffer, iterator.Value()); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		fields := structFields(value.Type())
+		packHeader(buffer, len(fields), 0x80, 0xde)
+		for _, field := range fields {
+			packString(buffer, field.name)
+			if err := packValue(buffer, value.Field(field.index)); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.Errorf("binary codec cannot encode %s", value.Type())
+	}
+	return nil
+}
+
+func packInt(buffer *bytes.Buffer, number int64) {
+	switch {
+	case number >= 0:
+		packUint(buffer, uint64(number))
+	case number >= -32:
+		buffer.WriteByte(byte(int8(number)))
+	case number >= math.MinInt8:
+		buffer.Write([]byte{0xd0, byte(int8(number))})
+	case number >= math.MinInt16:
+		buffer.WriteByte(0xd1)
+		binary.Write(buffer, binary.BigEndian, int16(number))
+	case number >= math.MinInt32:
+		buffer.WriteByte(0xd2)
+		binary.Write(buffer, binary.BigEndian, int32(number))
+	default:
+		buffer.WriteByte(0xd3)
+		binary.Write(buffer, binary.BigEndian, number)
+	}
+}
+
+func packUint(buffer *bytes.Buffer, number uint64) {
+	switch {
+	case number < 0x80:
+		buffer.WriteByte(byte(number))
+	case number <= math.MaxUint8:
+		buffer.Write([]byte{0xcc, byte(number)})
+	case number <= math.MaxUint16:
+		buffer.WriteByte(0xcd)
+		binary.Write(buffer, binary.BigEndian, uint16(number))
+	case number <= math.MaxUint32:
+		buffer.WriteByte(0xce)
+		binary.Write(buffer, binary.BigEndian, uint32(number))
+	default:
+		buffer.WriteByte(0xcf)
+		binary.Write(buffer, binary.BigEndian, number)
+	}
+}
+
+func packString(buffer *bytes.Buffer, text string) {
+	switch length := len(text); {
+	case length < 32:
+		buffer.WriteByte(0xa0 | byte(length))
+	case length <= math.MaxUint8:
+		buffer.Write([]byte{0xd9, byte(length)})
+	case length <= math.MaxUint16:
+		buffer.WriteByte(0xda)
+		binary.Write(buffer, binary.BigEndian, uint16(length))
+	default:
+		buffer.WriteByte(0xdb)
+		binary.Write(buffer, binary.BigEndian, uint32(length))
+	}
+	buffer.WriteString(text)
+}
+
+func packBytes(buffer *bytes.Buffer, data []byte) {
+	switch length := len(data); {
+	case length <= math.MaxUint8:
+		buffer.Write([]byte{0xc4, byte(length)})
+	case length <= math.MaxUint16:
+		buffer.WriteByte(0xc5)
+		binary.Write(buffer, binary.BigEndian, uint16(length))
+	default:
+		buffer.WriteByte(0xc6)
+		binary.Write(buffer, binary.BigEndian, uint32(length))
+	}
+	buffer.Write(data)
+}
+
+// packHeader writes an array or map header, fix is the fixarray or fixmap
+// prefix and wide the 16 bit variant, the 32 bit one follows it.
+func packHeader(buffer *bytes.Buffer, length int, fix byte, wide byte) {
+	switch {
+	case length < 16:
+		buffer.WriteByte(fix | byte(length))
+	case length <= math.MaxUint16:
+		buffer.WriteByte(wide)
+		binary.Write(buffer, binary.BigEndian, uint16(length))
+	default:
+		buffer.WriteByte(wide + 1)
+		binary.Write(buffer, binary.BigEndian, uint32(length))
+	}
+}
+
+type structField struct {
+	name  string
+	index int
+}
+
+// structFields lists the encoded name and index of every exported field in
+// declaration order, so a struct always encodes to the same bytes.
+func structFields(kind reflect.Type) []structField {
+	var fields []structField
+	for i := 0; i < kind.NumField(); i++ {
+		field := kind.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		fields = append(fields, structField{name: name, index: i})
+	}
+	return fields
+}
+
+// unpackValue reads one value into nil, bool, int64, uint64, float64, string,
+// []byte, []interface{} or a map, map[string]interface{} when every key is a
+// string and map[interface{}]interface{} otherwise.
+func unpackValue(reader *bytes.Reader) (interface{}, error) {
+	prefix, err := reader.ReadByte()
+	if err != nil {
+		return nil, errors.New("binary codec: unexpected end of data")
+	}
+	switch {
+	case prefix < 0x80:
+		return int64(prefix), nil
+	case prefix >= 0xe0:
+		return int64(int8(prefix)), nil
+	case prefix&0xf0 == 0x80:
+		return unpackMap(reader, int(prefix&0x0f))
+	case prefix&0xf0 == 0x90:
+		return unpackArray(reader, int(prefix&0x0f))
+	case prefix&0xe0 == 0xa0:
+		data, err := readBytes(reader, int(prefix&0x1f))
+		return string(data), err
+	}
+	switch prefix {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		length, err := readLength(reader, prefix-0xc4)
+		if err != nil {
+			return nil, err
+		}
+		return readBytes(reader, length)
+	case 0xca:
+		var bits uint32
+		err := binary.Read(reader, binary.BigEndian, &bits)
+		return float64(math.Float32frombits(bits)), err
+	case 0xcb:
+		var bits uint64
+		err := binary.Read(reader, binary.BigEndian, &bits)
+		return math.Float64frombits(bits), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		number, err := readUint(reader, 1<<(prefix-0xcc))
+		return number, err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		number, err := readUint(reader, 1<<(prefix-0xd0))
+		shift := 64 - 8*(1<<(prefix-0xd0))
+		return int64(number<<shift) >> shift, err
+	case 0xd9, 0xda, 0xdb:
+		length, err := readLength(reader, prefix-0xd9)
+		if err != nil {
+			return nil, err
+		}
+		data, err := readBytes(reader, length)
+		return string(data), err
+	case 0xdc, 0xdd:
+		length, err := readLength(reader, prefix-0xdc+1)
+		if err != nil {
+			return nil, err
+		}
+		return unpackArray(reader, length)
+	case 0xde, 0xdf:
+		length, err := readLength(reader, prefix-0xde+1)
+		if err != nil {
+			return nil, err
+		}
+		return unpackMap(reader, length)
+	}
+	return nil, errors.Errorf("binary codec: unsupported prefix 0x%x", prefix)
+}
+
+func unpackArray(reader *bytes.Reader, length int) (interface{}, error) {
+	if length > reader.Len() {
+		return nil, errors.New("binary codec: unexpected end of data")
+	}
+	items := make([]interface{}, length)
+	for i := range items {
+		item, err := unpackValue(reader)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+func unpackMap(reader *bytes.Reader, length int) (interface{}, error) {
+	if length > reader.Len() {
+		return nil, errors.New("binary codec: unexpected end of data")
+	}
+	keys := make([]interface{}, length)
+	values := make([]interface{}, length)
+	textKeys := true
+	for i := 0; i < length; i++ {
+		key, err := unpackValue(reader)
+		if err != nil {
+			return nil, err
+		}
+		switch key.(type) {
+		case string:
+		case []byte, []interface{}, map[string]interface{}, map[interface{}]interface{}:
+			key = stringKey(key)
+			textKeys = false
+		default:
+			textKeys = false
+		}
+		keys[i] = key
+		if values[i], err = unpackValue(reader); err != nil {
+			return nil, err
+		}
+	}
+	if textKeys {
+		result := make(map[string]interface{}, length)
+		for i, key := range keys {
+			result[key.(string)] = values[i]
+		}
+		return result, nil
+	}
+	result := make(map[interface{}]interface{}, length)
+	for i, key := range keys {
+		result[key] = values[i]
+	}
+	return result, nil
+}
+
+// stringKey stands in for map keys that cannot be Go map keys.
+func stringKey(key interface{}) string {
+	if data, ok := key.([]byte); ok {
+		return string(data)
+	}
+	data, _ := json.Marshal(key)
+	return string(data)
+}
+
+// readLength reads a big endian length of 1, 2 or 4 bytes for size 0, 1 or 2.
+func readLength(reader *bytes.Reader, size byte) (int, error) {
+	length, err := readUint(reader, 1<<size)
+	if err != nil {
+		return 0, err
+	}
+	return int(length), nil
+}
+
+func readUint(reader *bytes.Reader, size int) (uint64, error) {
+	data, err := readBytes(reader, size)
+	if err != nil {
+		return 0, err
+	}
+	var number uint64
+	for _, b := range data {
+		number = number<<8 | uint64(b)
+	}
+	return number, nil
+}
+
+func readBytes(reader *bytes.Reader, length int) ([]byte, error) {
+	if length > reader.Len() {
+		return nil, errors.New("binary codec: unexpected end of data")
+	}
+	data := make([]byte, length)
+	reader.Read(data)
+	return data, nil
+}
+
+// assignValue stores what unpackValue produced into target.
+func assignValue(target reflect.Value, value interface{}) error {
+	if data, ok := value.([]byte); ok && target.CanAddr() && target.Addr().Type().Implements(binaryUnmarshaler) {
+		return target.Addr().Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(data)
+	}
+	if value == nil {
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+	mismatch := errors.Errorf("binary codec cannot decode %T into %s", value, target.Type())
+	switch target.Kind() {
+	case reflect.Interface:
+		source := reflect.ValueOf(value)
+		if !source.Type().AssignableTo(target.Type()) {
+			return mismatch
+		}
+		target.Set(source)
+	case reflect.Ptr:
+		item := reflect.New(target.Type().Elem())
+		if err := assignValue(item.Elem(), value); err != nil {
+			return err
+		}
+		target.Set(item)
+	case reflect.Bool:
+		flag, ok := value.(bool)
+		if !ok {
+			return mismatch
+		}
+		target.SetBool(flag)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var number int64
+		switch source := value.(type) {
+		case int64:
+			number = source
+		case uint64:
+			if source > math.MaxInt64 {
+				return mismatch
+			}
+			number = int64(source)
+		default:
+			return mismatch
+		}
+		if target.OverflowInt(number) {
+			return mismatch
+		}
+		target.SetInt(number)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var number uint64
+		switch source := value.(type) {
+		case uint64:
+			number = source
+		case int64:
+			if source < 0 {
+				return mismatch
+			}
+			number = uint64(source)
+		default:
+			return mismatch
+		}
+		if target.OverflowUint(number) {
+			return mismatch
+		}
+		target.SetUint(number)
+	case reflect.Float32, reflect.Float64:
+		switch source := value.(type) {
+		case float64:
+			target.SetFloat(source)
+		case int64:
+			target.SetFloat(float64(source))
+		case uint64:
+			target.SetFloat(float64(source))
+		default:
+			return mismatch
+		}
+	case reflect.String:
+		switch source := value.(type) {
+		case string:
+			target.SetString(source)
+		case []byte:
+			target.SetString(string(source))
+		default:
+			return mismatch
+		}
+	case reflect.Slice:
+		if target.Type().Elem().Kind() == reflect.Uint8 {
+			switch source := value.(type) {
+			case []byte:
+				target.SetBytes(source)
+			case string:
+				target.SetBytes([]byte(source))
+			default:
+				return mismatch
+			}
+			return nil
+		}
+		items, ok := value.([]interface{})
+		if !ok {
+			return mismatch
+		}
+		slice := reflect.MakeSlice(target.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := assignValue(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		target.Set(slice)
+	case reflect.Array:
+		if data, ok := value.([]byte); ok && target.Type().Elem().Kind() == reflect.Uint8 {
+			reflect.Copy(target, reflect.ValueOf(data))
+			return nil
+		}
+		items, ok := value.([]interface{})
+		if !ok || len(items) > target.Len() {
+			return mismatch
+		}
+		for i, item := range items {
+			if err := assignValue(target.Index(i), item); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		result := reflect.MakeMap(target.Type())
+		assign := func(key interface{}, item interface{}) error {
+			mapKey := reflect.New(target.Type().Key()).Elem()
+			if err := assignValue(mapKey, key); err != nil {
+				return err
+			}
+			mapValue := reflect.New(target.Type().Elem()).Elem()
+			if err := assignValue(mapValue, item); err != nil {
+				return err
+			}
+			result.SetMapIndex(mapKey, mapValue)
+			return nil
+		}
+		switch source := value.(type) {
+		case map[string]interface{}:
+			for key, item := range source {
+				if err := assign(key, item); err != nil {
+					return err
+				}
+			}
+		case map[interface{}]interface{}:
+			for key, item := range source {
+				if err := assign(key, item); err != nil {
+					return err
+				}
+			}
+		default:
+			return mismatch
+		}
+		target.Set(result)
+	case reflect.Struct:
+		source, ok := value.(map[string]interface{})
+		if !ok {
+			return mismatch
+		}
+		for _, field := range structFields(target.Type()) {
+			if item, ok := source[field.name]; ok {
+				if err := assignValue(target.Field(field.index), item); err != nil {
+					return err
+				}
+			}
+		}
+	default:
+		return mismatch
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package list
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type codecInner struct {
+	Name  string
+	Tags  []string
+	Score float32
+}
+
+type codecRecord struct {
+	Small    int8
+	Medium   int32
+	Large    int64
+	Unsigned uint64
+	Negative int
+	Ratio    float64
+	At       time.Time
+	Data     []byte
+	Nested   map[string]map[string]int
+	Numbers  map[int]string
+	Inner    codecInner
+	Pointer  *codecInner
+	Renamed  string `json:"renamed"`
+	Skipped  string `json:"-"`
+	hidden   string
+}
+
+func testRecord() codecRecord {
+	return codecRecord{
+		Small:    -5,
+		Medium:   -70000,
+		Large:    math.MinInt64,
+		Unsigned: math.MaxUint64,
+		Negative: -129,
+		Ratio:    -1.5e-300,
+		At:       time.Date(2024, 2, 29, 12, 30, 0, 123456789, time.FixedZone("X", 3600)),
+		Data:     []byte{0, 1, 2},
+		Nested:   map[string]map[string]int{"a": {"x": 1, "y": 300}, "b": {}},
+		Numbers:  map[int]string{1: "one", -2: "minus two"},
+		Inner:    codecInner{Name: "inner", Tags: []string{"t"}, Score: 0.5},
+		Pointer:  &codecInner{Name: "pointer"},
+		Renamed:  "renamed",
+	}
+}
+
+func TestBinaryCodecRoundTrip(t *testing.T) {
+	codec := BinaryCodec{}
+	record := testRecord()
+	data, err := codec.Encode(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded codecRecord
+	if err := codec.Decode(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.At.Equal(record.At) {
+		t.Fatal(decoded.At, record.At)
+	}
+	decoded.At = record.At
+	if !reflect.DeepEqual(decoded, record) {
+		t.Fatalf("%+v\n%+v", decoded, record)
+	}
+
+	for _, value := range []interface{}{int64(0), int64(-32), int64(-33), int64(127), int64(128), int64(math.MaxInt64), uint64(math.MaxUint32 + 1), 3.25, math.Inf(-1)} {
+		data, err := codec.Encode(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		target := reflect.New(reflect.TypeOf(value))
+		if err := codec.Decode(data, target.Interface()); err != nil || target.Elem().Interface() != value {
+			t.Fatal(value, target.Elem().Interface(), err)
+		}
+	}
+}
+
+func TestBinaryCodecStructOrder(t *testing.T) {
+	codec := BinaryCodec{}
+	first, err := codec.Encode(codecInner{Name: "a", Tags: []string{"b"}, Score: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		data, _ := codec.Encode(codecInner{Name: "a", Tags: []string{"b"}, Score: 1})
+		if !bytes.Equal(data, first) {
+			t.Fatal("struct encoding is not deterministic")
+		}
+	}
+	// fixmap of 3, then the fields in declaration order
+	if first[0] != 0x83 || !bytes.HasPrefix(first[1:], []byte("\xa4Name")) {
+		t.Fatalf("%q", first)
+	}
+	if bytes.Index(first, []byte("Name")) > bytes.Index(first, []byte("Tags")) ||
+		bytes.Index(first, []byte("Tags")) > bytes.Index(first, []byte("Score")) {
+		t.Fatalf("%q", first)
+	}
+}
+
+func TestBinaryCodecTruncated(t *testing.T) {
+	codec := BinaryCodec{}
+	data, err := codec.Encode(testRecord())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for length := 0; length < len(data); length++ {
+		var decoded codecRecord
+		if err := codec.Decode(data[:length], &decoded); err == nil {
+			t.Fatal("truncated input decoded at", length)
+		}
+	}
+	var decoded codecRecord
+	if err := codec.Decode(append(data, 0), &decoded); err == nil {
+		t.Fatal("trailing data was accepted")
+	}
+}
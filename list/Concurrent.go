@@ -0,0 +1,403 @@
+package list
+
+import (
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"hash/maphash"
+	"math"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultShards is the shard count NewConcurrent uses for shards <= 0.
+var DefaultShards = 32
+
+// ConcurrentList is a keyed list for read heavy use. Elements are spread over
+// shards, each with its own RWMutex, so Find, Contains and Update of keys in
+// different shards do not wait for each other and lookups of the same shard
+// share a read lock. The order is guarded by one more RWMutex: walks like
+// Head, Next or Keys share it, inserts and removals take it exclusively
+// before the shard lock, so the links are always consistent.
+//
+// It has no storage, events or capacity, use List for those.
+type ConcurrentList[K comparable, V any] struct {
+	shards []concurrentShard[K, V]
+	seed   maphash.Seed
+	order  sync.RWMutex
+	head   *Component[K, V]
+	tail   *Component[K, V]
+	size   atomic.Int64
+}
+
+type concurrentShard[K comparable, V any] struct {
+	locker sync.RWMutex
+	items  map[K]*Component[K, V]
+}
+
+func NewConcurrent[K comparable, V any](shards int) *ConcurrentList[K, V] {
+	if shards <= 0 {
+		shards = DefaultShards
+	}
+	this := &ConcurrentList[K, V]{shards: make([]concurrentShard[K, V], shards), seed: maphash.MakeSeed()}
+	for index := range this.shards {
+		this.shards[index].items = make(map[K]*Component[K, V])
+	}
+	return this
+}
+
+func (this *ConcurrentList[K, V]) Find(target K) (element V) {
+	shard := this.shard(target)
+	shard.locker.RLock()
+	defer shard.locker.RUnlock()
+	if data, ok := shard.items[target]; ok {
+		element = data.Data
+	}
+	return
+}
+
+func (this *ConcurrentList[K, V]) Contains(target K) bool {
+	shard := this.shard(target)
+	shard.locker.RLock()
+	defer shard.locker.RUnlock()
+	_, ok := shard.items[target]
+	return ok
+}
+
+// Update changes the value of key in place, only its shard is locked.
+func (this *ConcurrentList[K, V]) Update(key K, data V) error {
+	shard := this.shard(key)
+	shard.locker.Lock()
+	defer shard.locker.Unlock()
+	if val, ok := shard.items[key]; ok {
+		val.Data = data
+		return nil
+	} else {
+		return errors.New("data not found")
+	}
+}
+
+func (this *ConcurrentList[K, V]) AddLast(key K, data V) error {
+	this.order.Lock()
+	defer this.order.Unlock()
+	return this.insertNoLock(key, data, this.linkLastNoLock)
+}
+
+func (this *ConcurrentList[K, V]) AddFirst(key K, data V) error {
+	this.order.Lock()
+	defer this.order.Unlock()
+	return this.insertNoLock(key, data, func(temp *Component[K, V]) {
+		if this.head == nil {
+			this.tail = temp
+		} else {
+			this.head.Prev = temp
+			temp.Next = this.head
+		}
+		this.head = temp
+	})
+}
+
+func (this *ConcurrentList[K, V]) AddAfter(key K, data V, target K) (bool, error) {
+	this.order.Lock()
+	defer this.order.Unlock()
+	other, ok := this.lookup(target)
+	if !ok {
+		return false, errors.New("target not found")
+	}
+	err := this.insertNoLock(key, data, func(temp *Component[K, V]) {
+		temp.Prev = other
+		temp.Next = other.Next
+		if other.Next == nil {
+			this.tail = temp
+		} else {
+			other.Next.Prev = temp
+		}
+		other.Next = temp
+	})
+	return err == nil, err
+}
+
+func (this *ConcurrentList[K, V]) AddBefore(key K, data V, target K) (bool, error) {
+	this.order.Lock()
+	defer this.order.Unlock()
+	other, ok := this.lookup(target)
+	if !ok {
+		return false, errors.New("target not found")
+	}
+	err := this.insertNoLock(key, data, func(temp *Component[K, V]) {
+		temp.Next = other
+		temp.Prev = other.Prev
+		if other.Prev == nil {
+			this.head = temp
+		} else {
+			other.Prev.Next = temp
+		}
+		other.Prev = temp
+	})
+	return err == nil, err
+}
+
+// AddLastOrUpdate updates key in place when it exists and appends it when not.
+func (this *ConcurrentList[K, V]) AddLastOrUpdate(key K, data V) {
+	if this.Update(key, data) == nil {
+		return
+	}
+	this.order.Lock()
+	defer this.order.Unlock()
+	if this.insertNoLock(key, data, this.linkLastNoLock) != nil {
+		// added between Update and the order lock
+		this.Update(key, data)
+	}
+}
+
+func (this *ConcurrentList[K, V]) Remove(target K) (element V) {
+	this.order.Lock()
+	defer this.order.Unlock()
+	if data, ok := this.lookup(target); ok {
+		_, element = this.removeNoLock(data)
+	}
+	return
+}
+
+func (this *ConcurrentList[K, V]) RemoveFirst() (key K, element V) {
+	this.order.Lock()
+	defer this.order.Unlock()
+	if this.head != nil {
+		return this.removeNoLock(this.head)
+	}
+	return
+}
+
+func (this *ConcurrentList[K, V]) RemoveLast() (key K, element V) {
+	this.order.Lock()
+	defer this.order.Unlock()
+	if this.tail != nil {
+		return this.removeNoLock(this.tail)
+	}
+	return
+}
+
+func (this *ConcurrentList[K, V]) Head() (key K, element V) {
+	this.order.RLock()
+	defer this.order.RUnlock()
+	return this.read(this.head)
+}
+
+func (this *ConcurrentList[K, V]) Tail() (key K, element V) {
+	this.order.RLock()
+	defer this.order.RUnlock()
+	return this.read(this.tail)
+}
+
+func (this *ConcurrentList[K, V]) Next(target K) (key K, element V) {
+	this.order.RLock()
+	defer this.order.RUnlock()
+	if data, ok := this.lookup(target); ok {
+		return this.read(data.Next)
+	}
+	return
+}
+
+func (this *ConcurrentList[K, V]) Prev(target K) (key K, element V) {
+	this.order.RLock()
+	defer this.order.RUnlock()
+	if data, ok := this.lookup(target); ok {
+		return this.read(data.Prev)
+	}
+	return
+}
+
+// Keys returns every key head first.
+func (this *ConcurrentList[K, V]) Keys() (keys []K) {
+	this.order.RLock()
+	defer this.order.RUnlock()
+	keys = make([]K, 0, this.size.Load())
+	for item := this.head; item != nil; item = item.Next {
+		keys = append(keys, item.Key)
+	}
+	return
+}
+
+func (this *ConcurrentList[K, V]) Contents() map[K]V {
+	this.order.RLock()
+	defer this.order.RUnlock()
+	for index := range this.shards {
+		this.shards[index].locker.RLock()
+		defer this.shards[index].locker.RUnlock()
+	}
+	content := make(map[K]V, this.size.Load())
+	for item := this.head; item != nil; item = item.Next {
+		content[item.Key] = item.Data
+	}
+	return content
+}
+
+func (this *ConcurrentList[K, V]) Size() int {
+	return int(this.size.Load())
+}
+
+// insertNoLock adds a new component to its shard and lets link put it in the
+// chain. The order lock has to be held.
+func (this *ConcurrentList[K, V]) insertNoLock(key K, data V, link func(temp *Component[K, V])) error {
+	shard := this.shard(key)
+	shard.locker.Lock()
+	defer shard.locker.Unlock()
+	if _, ok := shard.items[key]; ok {
+		return errors.New("duplicate key")
+	}
+	temp := &Component[K, V]{Data: data, Key: key}
+	shard.items[key] = temp
+	link(temp)
+	this.size.Add(1)
+	return nil
+}
+
+func (this *ConcurrentList[K, V]) linkLastNoLock(temp *Component[K, V]) {
+	if this.tail == nil {
+		this.head = temp
+	} else {
+		this.tail.Next = temp
+		temp.Prev = this.tail
+	}
+	this.tail = temp
+}
+
+// removeNoLock takes data out of its shard and the chain. The order lock has
+// to be held.
+func (this *ConcurrentList[K, V]) removeNoLock(data *Component[K, V]) (key K, element V) {
+	shard := this.shard(data.Key)
+	shard.locker.Lock()
+	delete(shard.items, data.Key)
+	key, element = data.Key, data.Data
+	shard.locker.Unlock()
+	if data.Prev == nil {
+		this.head = data.Next
+	} else {
+		data.Prev.Next = data.Next
+	}
+	if data.Next == nil {
+		this.tail = data.Prev
+	} else {
+		data.Next.Prev = data.Prev
+	}
+	this.size.Add(-1)
+	return
+}
+
+func (this *ConcurrentList[K, V]) lookup(key K) (*Component[K, V], bool) {
+	shard := this.shard(key)
+	shard.locker.RLock()
+	defer shard.locker.RUnlock()
+	data, ok := shard.items[key]
+	return data, ok
+}
+
+// read returns key and value of data, the value under its shard's lock.
+func (this *ConcurrentList[K, V]) read(data *Component[K, V]) (key K, element V) {
+	if data == nil {
+		return
+	}
+	shard := this.shard(data.Key)
+	shard.locker.RLock()
+	defer shard.locker.RUnlock()
+	return data.Key, data.Data
+}
+
+func (this *ConcurrentList[K, V]) shard(key K) *concurrentShard[K, V] {
+	return &this.shards[shardHash(this.seed, key)%uint64(len(this.shards))]
+}
+
+// shardHash hashes strings and numbers directly, other keys field by field,
+// so keys that are == always land in the same shard.
+func shardHash[K comparable](seed maphash.Seed, key K) uint64 {
+	switch value := any(key).(type) {
+	case string:
+		return maphash.String(seed, value)
+	case int:
+		return mixHash(uint64(value))
+	case int64:
+		return mixHash(uint64(value))
+	case int32:
+		return mixHash(uint64(value))
+	case uint:
+		return mixHash(uint64(value))
+	case uint64:
+		return mixHash(value)
+	case uint32:
+		return mixHash(uint64(value))
+	case float64:
+		return mixHash(floatBits(value))
+	case float32:
+		return mixHash(floatBits(float64(value)))
+	default:
+		var hash maphash.Hash
+		hash.SetSeed(seed)
+		writeHash(&hash, reflect.ValueOf(&key).Elem())
+		return hash.Sum64()
+	}
+}
+
+// writeHash feeds what == compares of value into hash.
+func writeHash(hash *maphash.Hash, value reflect.Value) {
+	var buffer [8]byte
+	word := func(bits uint64) {
+		binary.LittleEndian.PutUint64(buffer[:], bits)
+		hash.Write(buffer[:])
+	}
+	switch value.Kind() {
+	case reflect.Bool:
+		if value.Bool() {
+			word(1)
+		} else {
+			word(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		word(uint64(value.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		word(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		word(floatBits(value.Float()))
+	case reflect.Complex64, reflect.Complex128:
+		number := value.Complex()
+		word(floatBits(real(number)))
+		word(floatBits(imag(number)))
+	case reflect.String:
+		word(uint64(value.Len()))
+		hash.WriteString(value.String())
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		word(uint64(value.Pointer()))
+	case reflect.Array:
+		for index := 0; index < value.Len(); index++ {
+			writeHash(hash, value.Index(index))
+		}
+	case reflect.Struct:
+		for index := 0; index < value.NumField(); index++ {
+			writeHash(hash, value.Field(index))
+		}
+	case reflect.Interface:
+		if value.IsNil() {
+			word(0)
+			return
+		}
+		hash.WriteString(value.Elem().Type().String())
+		writeHash(hash, value.Elem())
+	}
+}
+
+// floatBits maps -0 onto 0, they are ==.
+func floatBits(value float64) uint64 {
+	if value == 0 {
+		value = 0
+	}
+	return math.Float64bits(value)
+}
+
+// mixHash spreads sequential integers over the shards (splitmix64 finalizer).
+func mixHash(value uint64) uint64 {
+	value ^= value >> 30
+	value *= 0xbf58476d1ce4e5b9
+	value ^= value >> 27
+	value *= 0x94d049bb133111eb
+	value ^= value >> 31
+	return value
+}
@@ -0,0 +1,132 @@
+package list
+
+import (
+	"math"
+	"sync/atomic"
+	"testing"
+)
+
+// benchList is what the benchmarks need of List and ConcurrentList.
+type benchList interface {
+	AddLast(key int, data int) error
+	Find(target int) int
+	Head() (int, int)
+	Keys() []int
+	Update(key int, data int) error
+	Remove(target int) int
+}
+
+const benchSize = 1000
+
+func fillBench(b *testing.B, list benchList) benchList {
+	for i := 0; i < benchSize; i++ {
+		if err := list.AddLast(i, i); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ResetTimer()
+	return list
+}
+
+func benchFind(b *testing.B, list benchList) {
+	fillBench(b, list)
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			list.Find(i % benchSize)
+			i++
+		}
+	})
+}
+
+func benchHead(b *testing.B, list benchList) {
+	fillBench(b, list)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			list.Head()
+		}
+	})
+}
+
+func benchKeys(b *testing.B, list benchList) {
+	fillBench(b, list)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			list.Keys()
+		}
+	})
+}
+
+// benchMixed does nine reads for every write, a write being an update or a
+// remove followed by an add of a new key.
+func benchMixed(b *testing.B, list benchList) {
+	fillBench(b, list)
+	var next atomic.Int64
+	next.Store(benchSize)
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := i % benchSize
+			switch i % 20 {
+			case 0:
+				list.Update(key, i)
+			case 10:
+				added := int(next.Add(1))
+				list.Remove(added - benchSize)
+				list.AddLast(added, i)
+			default:
+				list.Find(key)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkListFind(b *testing.B) {
+	benchFind(b, New[int, int]())
+}
+
+func BenchmarkConcurrentListFind(b *testing.B) {
+	benchFind(b, NewConcurrent[int, int](DefaultShards))
+}
+
+func BenchmarkListHead(b *testing.B) {
+	benchHead(b, New[int, int]())
+}
+
+func BenchmarkConcurrentListHead(b *testing.B) {
+	benchHead(b, NewConcurrent[int, int](DefaultShards))
+}
+
+func BenchmarkListKeys(b *testing.B) {
+	benchKeys(b, New[int, int]())
+}
+
+func BenchmarkConcurrentListKeys(b *testing.B) {
+	benchKeys(b, NewConcurrent[int, int](DefaultShards))
+}
+
+func BenchmarkListMixed(b *testing.B) {
+	benchMixed(b, New[int, int]())
+}
+
+func BenchmarkConcurrentListMixed(b *testing.B) {
+	benchMixed(b, NewConcurrent[int, int](DefaultShards))
+}
+
+func TestConcurrentListEqualKeys(t *testing.T) {
+	floats := NewConcurrent[float64, int](DefaultShards)
+	floats.AddLast(0.0, 1)
+	if err := floats.AddLast(math.Copysign(0, -1), 2); err == nil || floats.Size() != 1 {
+		t.Fatal("-0 and 0 were stored as separate keys")
+	}
+	type point struct {
+		X float64
+		Y interface{}
+	}
+	points := NewConcurrent[point, int](DefaultShards)
+	points.AddLast(point{0, "a"}, 1)
+	if points.Find(point{math.Copysign(0, -1), "a"}) != 1 {
+		t.Fatal("equal struct keys hashed apart")
+	}
+}
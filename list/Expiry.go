@@ -0,0 +1,219 @@
+package list
+
+import (
+	"container/heap"
+	"context"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// DefaultReapInterval is how often the reaper started implicitly by
+// AddLastWithTTL and SetTTL looks for expired elements.
+var DefaultReapInterval = time.Second
+
+// deadline is the heap entry of a key with a TTL, index is its place in the
+// heap so a new TTL or a removal can fix it in place.
+type deadline[K comparable] struct {
+	key   K
+	at    time.Time
+	index int
+}
+
+// deadlineHeap orders pending expirations by time, it holds one entry per key.
+type deadlineHeap[K comparable] []*deadline[K]
+
+func (this deadlineHeap[K]) Len() int           { return len(this) }
+func (this deadlineHeap[K]) Less(i, j int) bool { return this[i].at.Before(this[j].at) }
+
+func (this deadlineHeap[K]) Swap(i, j int) {
+	this[i], this[j] = this[j], this[i]
+	this[i].index = i
+	this[j].index = j
+}
+
+func (this *deadlineHeap[K]) Push(x interface{}) {
+	item := x.(*deadline[K])
+	item.index = len(*this)
+	*this = append(*this, item)
+}
+
+func (this *deadlineHeap[K]) Pop() interface{} {
+	old := *this
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*this = old[:len(old)-1]
+	return item
+}
+
+// AddLastWithTTL appends key like AddLast and expires it once ttl has passed.
+func (this *List[K, V]) AddLastWithTTL(key K, data V, ttl time.Duration) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if _, err := this.addLastNoLock(key, data); err != nil {
+		return err
+	}
+	this.setTTLNoLock(key, ttl)
+	return nil
+}
+
+// SetTTL expires an existing key once d has passed, d <= 0 clears its TTL.
+func (this *List[K, V]) SetTTL(key K, d time.Duration) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if _, ok := this.container[key]; !ok {
+		return errors.New("data not found")
+	}
+	this.setTTLNoLock(key, d)
+	return nil
+}
+
+// TTL returns how long key has left before it expires, ok is false when the
+// key is unknown or never expires.
+func (this *List[K, V]) TTL(key K) (remaining time.Duration, ok bool) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if item, found := this.expires[key]; found {
+		return time.Until(item.at), true
+	}
+	return 0, false
+}
+
+// SetExpireFromStorage decides how expired elements are dropped. They are
+// removed like DeleteFromStorage(key, fromStorage), so with fromStorage true the
+// element stays in storage and only leaves the list. Default is false.
+func (this *List[K, V]) SetExpireFromStorage(fromStorage bool) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	this.expireKeep = fromStorage
+}
+
+// StartReaper runs the background goroutine that expires elements every
+// interval until ctx is done or Close is called. It is a no-op when a reaper
+// is already running.
+func (this *List[K, V]) StartReaper(ctx context.Context, interval time.Duration) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	this.startReaperNoLock(ctx, interval)
+}
+
+// Close stops the reaper and the other background goroutines of the list,
+// waits for them to exit and flushes the writes queued by write-behind, which
+// is turned off.
+func (this *List[K, V]) Close() error {
+	this.locker.Lock()
+	stop := this.stop
+	this.stop = nil
+	this.reaping = false
+	this.repairing = false
+	this.flushing = false
+	this.writeBehind = false
+	this.locker.Unlock()
+	if stop != nil {
+		close(stop)
+	}
+	this.workers.Wait()
+	return this.flushAll()
+}
+
+// startWorkerNoLock runs work in a goroutine that Close stops and waits for.
+func (this *List[K, V]) startWorkerNoLock(work func(stop <-chan struct{})) {
+	if this.stop == nil {
+		this.stop = make(chan struct{})
+	}
+	stop := this.stop
+	this.workers.Add(1)
+	go func() {
+		defer this.workers.Done()
+		work(stop)
+	}()
+}
+
+func (this *List[K, V]) startReaperNoLock(ctx context.Context, interval time.Duration) {
+	if this.reaping {
+		return
+	}
+	if interval <= 0 {
+		interval = DefaultReapInterval
+	}
+	this.reaping = true
+	this.startWorkerNoLock(func(stop <-chan struct{}) {
+		this.reap(ctx, interval, stop)
+	})
+}
+
+func (this *List[K, V]) reap(ctx context.Context, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			this.locker.Lock()
+			if this.stop == stop {
+				this.reaping = false
+			}
+			this.locker.Unlock()
+			return
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			this.locker.Lock()
+			this.reapNoLock(now)
+			this.locker.Unlock()
+		}
+	}
+}
+
+func (this *List[K, V]) setTTLNoLock(key K, d time.Duration) {
+	if d <= 0 {
+		this.clearDeadlineNoLock(key)
+		return
+	}
+	this.setDeadlineNoLock(key, time.Now().Add(d))
+	this.startReaperNoLock(context.Background(), DefaultReapInterval)
+}
+
+func (this *List[K, V]) setDeadlineNoLock(key K, at time.Time) {
+	if item, ok := this.expires[key]; ok {
+		item.at = at
+		heap.Fix(&this.deadlines, item.index)
+		return
+	}
+	if this.expires == nil {
+		this.expires = make(map[K]*deadline[K])
+	}
+	item := &deadline[K]{key: key, at: at}
+	this.expires[key] = item
+	heap.Push(&this.deadlines, item)
+}
+
+func (this *List[K, V]) clearDeadlineNoLock(key K) {
+	if item, ok := this.expires[key]; ok {
+		heap.Remove(&this.deadlines, item.index)
+		delete(this.expires, key)
+	}
+}
+
+func (this *List[K, V]) reapNoLock(now time.Time) {
+	for this.deadlines.Len() > 0 && !this.deadlines[0].at.After(now) {
+		key := this.deadlines[0].key
+		if data, ok := this.container[key]; ok {
+			this.expireNoLock(data)
+		} else {
+			this.clearDeadlineNoLock(key)
+		}
+	}
+}
+
+func (this *List[K, V]) expiredNoLock(key K, now time.Time) bool {
+	item, ok := this.expires[key]
+	return ok && !item.at.After(now)
+}
+
+// expireNoLock drops data even when the storage delete fails, see purgeNoLock.
+func (this *List[K, V]) expireNoLock(data *Component[K, V]) {
+	if !this.expireKeep {
+		this.purgeNoLock(data.Key)
+	}
+	this.unlinkNoLock(data)
+	this.broadcastEvent(this.newEventNoLock(EXPIRE, data))
+}
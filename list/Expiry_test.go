@@ -0,0 +1,47 @@
+package list
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetTTLKeepsOneDeadlinePerKey(t *testing.T) {
+	list := New[string, int]()
+	defer list.Close()
+	list.AddLast("a", 1)
+	list.AddLast("b", 2)
+	for i := 0; i < 100; i++ {
+		list.SetTTL("a", time.Hour)
+		list.SetTTL("b", time.Duration(i+1)*time.Second)
+	}
+	if len(list.deadlines) != 2 || len(list.expires) != 2 {
+		t.Fatal(len(list.deadlines), len(list.expires))
+	}
+	if list.deadlines[0].key != "b" {
+		t.Fatal(list.deadlines[0].key)
+	}
+	list.SetTTL("a", 0)
+	list.Remove("b")
+	if len(list.deadlines) != 0 || len(list.expires) != 0 {
+		t.Fatal(len(list.deadlines), len(list.expires))
+	}
+}
+
+func TestReapExpiresInOrder(t *testing.T) {
+	list := New[string, int]()
+	defer list.Close()
+	list.AddLast("a", 1)
+	list.AddLast("b", 2)
+	list.AddLast("c", 3)
+	list.SetTTL("a", time.Hour)
+	list.SetTTL("b", time.Minute)
+	list.SetTTL("c", 2*time.Hour)
+	list.SetTTL("a", time.Second)
+
+	list.locker.Lock()
+	list.reapNoLock(time.Now().Add(90 * time.Minute))
+	list.locker.Unlock()
+	if list.Size() != 1 || list.Find("c") != 3 || len(list.deadlines) != 1 {
+		t.Fatal(list.Size(), len(list.deadlines))
+	}
+}
@@ -0,0 +1,386 @@
+package list
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// CheckedStorage is a Storage whose writes report failures. A plain Storage is
+// wrapped by Checked, it never fails.
+type CheckedStorage[K comparable] interface {
+	Add(key K, data []byte) error
+	Delete(key K) error
+	Update(key K, data []byte) error
+	Get(key K) (interface{}, error)
+}
+
+// FAILURE decides what a list does when a storage write fails.
+type FAILURE int
+
+const (
+	ROLLBACK FAILURE = iota // leave the list unchanged and return the error
+	RETRY                   // retry with a doubling backoff, then roll back
+	QUEUE                   // keep the change and queue the write for Repair
+)
+
+// StorageError is returned, and given to the storage error handler, when a
+// write did not reach storage. Op is "add", "update", "delete", "get",
+// "batch", "move" or "reorder", Key is only set for a single write.
+type StorageError[K comparable] struct {
+	Op  string
+	Key K
+	Err error
+}
+
+func (this *StorageError[K]) Error() string {
+	return fmt.Sprintf("storage %s %v: %v", this.Op, this.Key, this.Err)
+}
+
+func (this *StorageError[K]) Unwrap() error {
+	return this.Err
+}
+
+// Checked adapts a Storage to CheckedStorage. Reorder and Iterate are passed
+// through when storage implements them.
+func Checked[K comparable](storage Storage[K]) CheckedStorage[K] {
+	if storage == nil {
+		return nil
+	}
+	return uncheckedStorage[K]{storage}
+}
+
+type uncheckedStorage[K comparable] struct {
+	storage Storage[K]
+}
+
+func (this uncheckedStorage[K]) Add(key K, data []byte) error {
+	this.storage.Add(key, data)
+	return nil
+}
+
+func (this uncheckedStorage[K]) Delete(key K) error {
+	this.storage.Delete(key)
+	return nil
+}
+
+func (this uncheckedStorage[K]) Update(key K, data []byte) error {
+	this.storage.Update(key, data)
+	return nil
+}
+
+func (this uncheckedStorage[K]) Get(key K) (interface{}, error) {
+	return this.storage.Get(key), nil
+}
+
+func (this uncheckedStorage[K]) Reorder(keys []K) {
+	if ordered, ok := this.storage.(OrderedStorage[K]); ok {
+		ordered.Reorder(keys)
+	}
+}
+
+func (this uncheckedStorage[K]) Iterate(fn func(key K, data interface{}) bool) error {
+	if iterable, ok := this.storage.(IterableStorage[K]); ok {
+		return iterable.Iterate(fn)
+	}
+	return errors.New("storage cannot iterate")
+}
+
+// batchStorage returns storage, or the Storage that Checked wrapped, as a
+// BatchStorage.
+func batchStorage[K comparable](storage CheckedStorage[K]) (BatchStorage[K], bool) {
+	if unchecked, ok := storage.(uncheckedStorage[K]); ok {
+		batcher, ok := unchecked.storage.(BatchStorage[K])
+		return batcher, ok
+	}
+	batcher, ok := storage.(BatchStorage[K])
+	return batcher, ok
+}
+
+// orderedStorage and positionStorage are batchStorage for OrderedStorage and
+// PositionStorage.
+func orderedStorage[K comparable](storage CheckedStorage[K]) (OrderedStorage[K], bool) {
+	if unchecked, ok := storage.(uncheckedStorage[K]); ok {
+		ordered, ok := unchecked.storage.(OrderedStorage[K])
+		return ordered, ok
+	}
+	ordered, ok := storage.(OrderedStorage[K])
+	return ordered, ok
+}
+
+func positionStorage[K comparable](storage CheckedStorage[K]) (PositionStorage[K], bool) {
+	if unchecked, ok := storage.(uncheckedStorage[K]); ok {
+		positions, ok := unchecked.storage.(PositionStorage[K])
+		return positions, ok
+	}
+	positions, ok := storage.(PositionStorage[K])
+	return positions, ok
+}
+
+func NewWithCheckedStorage[K comparable, V any](storage CheckedStorage[K]) *List[K, V] {
+	return &List[K, V]{container: make(map[K]*Component[K, V]), storage: storage}
+}
+
+func NewLRUWithCheckedStorage[K comparable, V any](capacity int, storage CheckedStorage[K]) *List[K, V] {
+	return &List[K, V]{container: make(map[K]*Component[K, V]), storage: storage, capacity: capacity}
+}
+
+// SetFailurePolicy decides how failed storage writes are handled. With RETRY a
+// write is tried retries more times, sleeping backoff, then twice as long and
+// so on, while the list stays locked. The sleeps of one write stop once they
+// add up to MaxRetryWait, so readers and writers wait at most that long, longer
+// outages call for QUEUE. With QUEUE a positive backoff is the interval of a
+// background Repair, otherwise Repair has to be called.
+//
+// Writes the list makes on its own, eviction and expiry, always go ahead in
+// memory. Under ROLLBACK and RETRY their failures only reach the handler.
+func (this *List[K, V]) SetFailurePolicy(policy FAILURE, retries int, backoff time.Duration) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	this.failure = policy
+	this.retries = retries
+	this.backoff = backoff
+	if policy == QUEUE && backoff > 0 && !this.repairing {
+		this.repairing = true
+		this.startWorkerNoLock(func(stop <-chan struct{}) {
+			this.repairEvery(backoff, stop)
+		})
+	}
+}
+
+// SetStorageErrorHandler registers a function that is called with every write
+// that failed after its retries and with every failed Get. It runs with the
+// list locked and must not call back into the list.
+func (this *List[K, V]) SetStorageErrorHandler(handler func(err *StorageError[K])) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	this.storageErrorHandler = handler
+}
+
+// Repair sends the writes queued under the QUEUE policy to storage, oldest
+// first. It stops at the first one that fails again and returns its error.
+func (this *List[K, V]) Repair() error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	err := this.repairs.drain(func(write StorageWrite[K]) error {
+		if err := this.sendNoLock(write); err != nil {
+			return &StorageError[K]{Op: write.Op, Key: write.Key, Err: err}
+		}
+		return nil
+	})
+	if err == nil {
+		this.storeOrderNoLock()
+	}
+	return err
+}
+
+// Pending returns how many writes wait for Repair.
+func (this *List[K, V]) Pending() int {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return this.repairs.len()
+}
+
+func (this *List[K, V]) repairEvery(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			this.Repair()
+		}
+	}
+}
+
+// writeNoLock hands one write to storage under the failure policy. Callers
+// change the list only when it returns nil. A key with a queued write queues
+// every later write too, so Repair cannot overwrite newer data.
+func (this *List[K, V]) writeNoLock(write StorageWrite[K]) error {
+	if this.storage == nil {
+		return nil
+	}
+	this.forgetNoLock(write.Key)
+	if this.repairs.pending(write.Key) {
+		this.repairs.put(write)
+		return nil
+	}
+	if this.behindNoLock(write) {
+		return nil
+	}
+	err := this.retryNoLock(func() error {
+		return this.sendNoLock(write)
+	})
+	if err == nil {
+		return nil
+	}
+	failure := &StorageError[K]{Op: write.Op, Key: write.Key, Err: err}
+	this.reportNoLock(failure)
+	if this.failure == QUEUE {
+		this.repairs.put(write)
+		return nil
+	}
+	return failure
+}
+
+// MaxRetryWait bounds how long RETRY sleeps in total for one write, the list
+// stays locked meanwhile.
+var MaxRetryWait = 100 * time.Millisecond
+
+// retryNoLock calls send once, or as often as the RETRY policy allows until
+// it succeeds, and returns its last error. The lock is kept while it sleeps,
+// the caller has already checked the list for the change it is about to make,
+// so the sleeps end after MaxRetryWait.
+func (this *List[K, V]) retryNoLock(send func() error) (err error) {
+	attempts := 1
+	if this.failure == RETRY && this.retries > 0 {
+		attempts += this.retries
+	}
+	backoff := this.backoff
+	var waited time.Duration
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 && backoff > 0 {
+			if waited >= MaxRetryWait {
+				return
+			}
+			sleep := min(backoff, MaxRetryWait-waited)
+			time.Sleep(sleep)
+			waited += sleep
+			backoff *= 2
+		}
+		if err = send(); err == nil {
+			return nil
+		}
+	}
+	return
+}
+
+func (this *List[K, V]) sendNoLock(write StorageWrite[K]) error {
+	return sendWrite(this.storage, write)
+}
+
+func sendWrite[K comparable](storage CheckedStorage[K], write StorageWrite[K]) error {
+	switch write.Op {
+	case "add":
+		return storage.Add(write.Key, write.Data)
+	case "update":
+		return storage.Update(write.Key, write.Data)
+	default:
+		return storage.Delete(write.Key)
+	}
+}
+
+func (this *List[K, V]) reportNoLock(err *StorageError[K]) {
+	if this.storageErrorHandler != nil {
+		this.storageErrorHandler(err)
+	}
+}
+
+// StorageWrite is one write a list owes its storage. Op is "add", "update" or
+// "delete", Data is the encoded value and nil for a delete.
+type StorageWrite[K comparable] struct {
+	Op   string
+	Key  K
+	Data []byte
+}
+
+// BatchStorage is implemented by storages that can apply several writes at
+// once, all or nothing. Batch and write-behind flushes use it when present.
+type BatchStorage[K comparable] interface {
+	Batch(writes []StorageWrite[K]) error
+}
+
+// writeQueue keeps writes in order and merges a write into the one already
+// waiting for its key: the newest payload wins and a delete turns a queued add
+// into a delete, storage may have held the key before the add. A write after a
+// delete is kept apart so the delete still happens first.
+type writeQueue[K comparable] struct {
+	writes []StorageWrite[K]
+	last   map[K]int
+	count  int
+}
+
+func (this *writeQueue[K]) put(write StorageWrite[K]) {
+	if index, ok := this.last[write.Key]; ok {
+		pending := &this.writes[index]
+		switch {
+		case pending.Op == "add" && write.Op == "delete":
+			// an older delete of the key still queued already does the job
+			for earlier := index - 1; earlier >= 0; earlier-- {
+				if this.writes[earlier].Op != "" && this.writes[earlier].Key == write.Key {
+					pending.Op = ""
+					this.count--
+					this.last[write.Key] = earlier
+					return
+				}
+			}
+			pending.Op, pending.Data = "delete", nil
+			return
+		case pending.Op == "add":
+			pending.Data = write.Data
+			return
+		case pending.Op == "update":
+			pending.Op, pending.Data = write.Op, write.Data
+			return
+		case write.Op == "delete":
+			return
+		}
+	}
+	if this.last == nil {
+		this.last = make(map[K]int)
+	}
+	this.last[write.Key] = len(this.writes)
+	this.writes = append(this.writes, write)
+	this.count++
+}
+
+func (this *writeQueue[K]) pending(key K) bool {
+	_, ok := this.last[key]
+	return ok
+}
+
+func (this *writeQueue[K]) latest(key K) (write StorageWrite[K], ok bool) {
+	var index int
+	if index, ok = this.last[key]; ok {
+		write = this.writes[index]
+	}
+	return
+}
+
+// list returns the queued writes in order.
+func (this *writeQueue[K]) list() []StorageWrite[K] {
+	writes := make([]StorageWrite[K], 0, this.count)
+	for _, write := range this.writes {
+		if write.Op != "" {
+			writes = append(writes, write)
+		}
+	}
+	return writes
+}
+
+func (this *writeQueue[K]) len() int {
+	return this.count
+}
+
+// drain passes the writes to send in order and forgets the ones that were
+// sent. On an error the rest stays queued.
+func (this *writeQueue[K]) drain(send func(write StorageWrite[K]) error) error {
+	for index, write := range this.writes {
+		if write.Op == "" {
+			continue
+		}
+		if err := send(write); err != nil {
+			rest := this.writes[index:]
+			this.writes, this.last, this.count = nil, nil, 0
+			for _, write := range rest {
+				if write.Op != "" {
+					this.put(write)
+				}
+			}
+			return err
+		}
+	}
+	this.writes, this.last, this.count = nil, nil, 0
+	return nil
+}
@@ -0,0 +1,259 @@
+package list
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testStorage is a CheckedStorage in memory that fails every call while down
+// and the write numbered failAt.
+type testStorage struct {
+	locker sync.Mutex
+	data   map[string][]byte
+	down   bool
+	calls  int
+	failAt int
+}
+
+func newTestStorage() *testStorage {
+	return &testStorage{data: make(map[string][]byte)}
+}
+
+func (this *testStorage) Add(key string, data []byte) error {
+	return this.set(key, data)
+}
+
+func (this *testStorage) Update(key string, data []byte) error {
+	return this.set(key, data)
+}
+
+func (this *testStorage) Delete(key string) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	this.calls++
+	if this.down || this.calls == this.failAt {
+		return os.ErrClosed
+	}
+	delete(this.data, key)
+	return nil
+}
+
+func (this *testStorage) Get(key string) (interface{}, error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if this.down {
+		return nil, os.ErrClosed
+	}
+	if data, ok := this.data[key]; ok {
+		return data, nil
+	}
+	return nil, nil
+}
+
+func (this *testStorage) set(key string, data []byte) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	this.calls++
+	if this.down || this.calls == this.failAt {
+		return os.ErrClosed
+	}
+	this.data[key] = data
+	return nil
+}
+
+func (this *testStorage) setDown(down bool) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	this.down = down
+}
+
+func (this *testStorage) get(key string) (string, bool) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	data, ok := this.data[key]
+	return string(data), ok
+}
+
+func TestWriteQueueCancelKeepsOlderWrite(t *testing.T) {
+	var queue writeQueue[string]
+	queue.put(StorageWrite[string]{Op: "delete", Key: "k"})
+	queue.put(StorageWrite[string]{Op: "add", Key: "k", Data: []byte("1")})
+	queue.put(StorageWrite[string]{Op: "delete", Key: "k"})
+	if write, ok := queue.latest("k"); !ok || write.Op != "delete" || queue.len() != 1 {
+		t.Fatal(write, ok, queue.len())
+	}
+	queue.put(StorageWrite[string]{Op: "add", Key: "k", Data: []byte("2")})
+	writes := queue.list()
+	if len(writes) != 2 || writes[0].Op != "delete" || writes[1].Op != "add" || string(writes[1].Data) != "2" {
+		t.Fatal(writes)
+	}
+}
+
+func TestWriteQueueAddDeleteKeepsDelete(t *testing.T) {
+	var queue writeQueue[string]
+	queue.put(StorageWrite[string]{Op: "add", Key: "k", Data: []byte("1")})
+	queue.put(StorageWrite[string]{Op: "delete", Key: "k"})
+	writes := queue.list()
+	if len(writes) != 1 || writes[0].Op != "delete" || writes[0].Data != nil {
+		t.Fatal(writes)
+	}
+	queue.put(StorageWrite[string]{Op: "add", Key: "k", Data: []byte("2")})
+	if writes := queue.list(); len(writes) != 2 || writes[1].Op != "add" {
+		t.Fatal(writes)
+	}
+}
+
+// storage already holds the key when an add and a delete of it are merged,
+// the delete has to reach it.
+func TestMergedDeleteOfStoredKey(t *testing.T) {
+	check := func(name string, storage *testStorage, list *List[string, int]) {
+		t.Helper()
+		if data, ok := storage.get("a"); ok {
+			t.Fatal(name, "left", data, "in storage")
+		}
+		if list.Find("a") != 0 {
+			t.Fatal(name, "brought a back")
+		}
+	}
+
+	storage := newTestStorage()
+	storage.data["a"] = []byte("1")
+	list := NewWithCheckedStorage[string, int](storage)
+	list.SetWriteBehind(0, time.Hour)
+	list.AddLast("a", 2)
+	list.Remove("a")
+	if err := list.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	check("write-behind", storage, list)
+	list.Close()
+
+	storage = newTestStorage()
+	storage.data["a"] = []byte("1")
+	list = NewWithCheckedStorage[string, int](storage)
+	list.SetFailurePolicy(QUEUE, 0, 0)
+	storage.setDown(true)
+	list.AddLast("a", 2)
+	list.Remove("a")
+	storage.setDown(false)
+	if err := list.Repair(); err != nil {
+		t.Fatal(err)
+	}
+	check("repair", storage, list)
+
+	storage = newTestStorage()
+	storage.data["a"] = []byte("1")
+	list = NewWithCheckedStorage[string, int](storage)
+	err := list.Batch(func(tx *ListTx[string, int]) error {
+		if err := tx.AddLast("a", 2); err != nil {
+			return err
+		}
+		tx.Remove("a")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("batch", storage, list)
+}
+
+func TestRepairAfterCancelledAdd(t *testing.T) {
+	storage := newTestStorage()
+	list := NewWithCheckedStorage[string, int](storage)
+	list.SetFailurePolicy(QUEUE, 0, 0)
+	list.AddLast("k", 1)
+	storage.setDown(true)
+	list.Remove("k")
+	list.AddLast("k", 2)
+	list.Remove("k")
+	storage.setDown(false)
+	if err := list.AddLast("k", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.Repair(); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := storage.get("k"); !ok || data != "3" {
+		t.Fatal(data, ok)
+	}
+	if list.Find("k") != 3 || list.Pending() != 0 {
+		t.Fatal(list.Find("k"), list.Pending())
+	}
+}
+
+func TestRemoveWithErrorVariants(t *testing.T) {
+	storage := newTestStorage()
+	list := NewWithCheckedStorage[string, int](storage)
+	list.AddLast("a", 1)
+	list.AddLast("b", 2)
+	storage.setDown(true)
+	if _, _, err := list.RemoveFirstWithError(); err == nil {
+		t.Fatal("RemoveFirstWithError hid the storage error")
+	}
+	if _, _, err := list.RemoveLastWithError(); err == nil {
+		t.Fatal("RemoveLastWithError hid the storage error")
+	}
+	if _, _, err := list.RemoveAfterWithError("a"); err == nil {
+		t.Fatal("RemoveAfterWithError hid the storage error")
+	}
+	if _, _, err := list.RemoveBeforeWithError("b"); err == nil {
+		t.Fatal("RemoveBeforeWithError hid the storage error")
+	}
+	if added, err := list.AddLastOnExistIgnoreWithError("c", 3); added || err == nil {
+		t.Fatal(added, err)
+	}
+	if list.Size() != 2 {
+		t.Fatal(list.Size())
+	}
+	storage.setDown(false)
+	if added, err := list.AddLastOnExistIgnoreWithError("a", 3); added || err != nil {
+		t.Fatal(added, err)
+	}
+	if key, element, err := list.RemoveFirstWithError(); key != "a" || element != 1 || err != nil {
+		t.Fatal(key, element, err)
+	}
+	if _, ok := storage.get("a"); ok {
+		t.Fatal("a is still stored")
+	}
+}
+
+func TestRetryWaitIsBounded(t *testing.T) {
+	storage := newTestStorage()
+	list := NewWithCheckedStorage[string, int](storage)
+	list.SetFailurePolicy(RETRY, 20, 10*time.Millisecond)
+	storage.setDown(true)
+	start := time.Now()
+	if err := list.AddLast("a", 1); err == nil {
+		t.Fatal("add went through with storage down")
+	}
+	if elapsed := time.Since(start); elapsed > MaxRetryWait+time.Second {
+		t.Fatal(elapsed)
+	}
+	// 10+20+40 ms, then the last 30 ms of MaxRetryWait
+	if storage.calls != 5 || list.Size() != 0 {
+		t.Fatal(storage.calls, list.Size())
+	}
+}
+
+func TestEvictPurgeFailureIsReported(t *testing.T) {
+	storage := newTestStorage()
+	list := NewLRUWithCheckedStorage[string, int](1, storage)
+	list.SetPurgeOnEvict(true)
+	var failures []*StorageError[string]
+	list.SetStorageErrorHandler(func(err *StorageError[string]) {
+		failures = append(failures, err)
+	})
+	list.AddLast("a", 1)
+	storage.failAt = storage.calls + 2
+	if err := list.AddLast("b", 2); err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) != 1 || failures[0].Op != "delete" || failures[0].Key != "a" {
+		t.Fatal(failures)
+	}
+	if _, ok := storage.get("a"); !ok || list.Size() != 1 {
+		t.Fatal("a was not kept in storage or not evicted")
+	}
+}
@@ -0,0 +1,610 @@
+package list
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FSYNC decides when FileStorage flushes its log to disk.
+type FSYNC int
+
+const (
+	SYNC_ALWAYS   FSYNC = iota // after every record
+	SYNC_INTERVAL              // every FileStorageOptions.SyncInterval
+	SYNC_NONE                  // left to the operating system
+)
+
+const (
+	recordAdd byte = iota + 1
+	recordUpdate
+	recordDelete
+)
+
+// a record is crc32(rest) | op | key length | data length | key | data
+const recordHeader = 4 + 1 + 4 + 4
+
+const (
+	maxRecordKey  = 1 << 20
+	maxRecordData = 1 << 30
+)
+
+var (
+	errCorruptHeader = errors.New("corrupt record header")
+	errCorruptRecord = errors.New("corrupt record")
+)
+
+type FileStorageOptions struct {
+	Sync         FSYNC
+	SyncInterval time.Duration // default one second
+	// CompactRatio is the share of the log taken by overwritten and deleted
+	// records that starts a compaction, default 0.5.
+	CompactRatio float64
+	// CompactMinSize keeps small logs from being compacted, default 1MB.
+	CompactMinSize int64
+}
+
+// FileStorage is a Storage that appends every Add, Update and Delete to a log
+// file. The index of live records is rebuilt from the log on open, a torn
+// record at the end of the file is cut off, a damaged one anywhere else makes
+// OpenFileStorage fail. When too much of the log is
+// garbage it is rewritten in the background with only the live records,
+// without blocking reads and writes for the copy.
+type FileStorage[K comparable] struct {
+	path    string
+	options FileStorageOptions
+	locker  sync.Mutex
+	file    *os.File
+	size    int64
+	live    int64
+	index   map[K]filePosition
+	next    int64
+	dirty   bool
+	closing bool
+	err     error
+	compact chan struct{}
+	done    chan struct{}
+	group   sync.WaitGroup
+
+	compactLocker sync.Mutex
+}
+
+type filePosition struct {
+	offset int64 // of the data, not the record
+	length int64
+	record int64 // size of the whole record
+	order  int64 // when the key was first written, updates keep it
+}
+
+func OpenFileStorage[K comparable](path string, options FileStorageOptions) (*FileStorage[K], error) {
+	if options.SyncInterval <= 0 {
+		options.SyncInterval = time.Second
+	}
+	if options.CompactRatio <= 0 {
+		options.CompactRatio = 0.5
+	}
+	if options.CompactMinSize <= 0 {
+		options.CompactMinSize = 1 << 20
+	}
+	this := &FileStorage[K]{
+		path:    path,
+		options: options,
+		compact: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	if err := this.open(); err != nil {
+		return nil, err
+	}
+	this.group.Add(1)
+	go this.background()
+	return this, nil
+}
+
+func (this *FileStorage[K]) Add(key K, data []byte) {
+	this.write(recordAdd, key, data)
+}
+
+func (this *FileStorage[K]) Update(key K, data []byte) {
+	this.write(recordUpdate, key, data)
+}
+
+func (this *FileStorage[K]) Delete(key K) {
+	this.write(recordDelete, key, nil)
+}
+
+// Get returns the stored payload as []byte, nil when key is unknown.
+func (this *FileStorage[K]) Get(key K) interface{} {
+	if data, _ := this.read(key); data != nil {
+		return data
+	}
+	return nil
+}
+
+// Checked returns a view of the storage whose methods report their errors,
+// for NewWithCheckedStorage.
+func (this *FileStorage[K]) Checked() CheckedStorage[K] {
+	return checkedFileStorage[K]{this}
+}
+
+// Iterate calls fn with every stored key and payload in the order the keys
+// were first added, until fn returns false. The payloads are read up front,
+// fn runs without the storage locked.
+func (this *FileStorage[K]) Iterate(fn func(key K, data interface{}) bool) error {
+	this.locker.Lock()
+	if this.file == nil {
+		this.locker.Unlock()
+		return errors.New("file storage is closed")
+	}
+	keys := this.orderedNoLock()
+	payloads := make([][]byte, len(keys))
+	for index, key := range keys {
+		position := this.index[key]
+		payloads[index] = make([]byte, position.length)
+		if _, err := this.file.ReadAt(payloads[index], position.offset); err != nil {
+			this.err = errors.Wrap(err, "read record")
+			this.locker.Unlock()
+			return this.err
+		}
+	}
+	this.locker.Unlock()
+	for index, key := range keys {
+		if !fn(key, payloads[index]) {
+			break
+		}
+	}
+	return nil
+}
+
+func (this *FileStorage[K]) Len() int {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return len(this.index)
+}
+
+// Err returns the last write or read error, Storage has no way to report it.
+func (this *FileStorage[K]) Err() error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return this.err
+}
+
+// Sync flushes the log to disk regardless of the FSYNC option.
+func (this *FileStorage[K]) Sync() error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return this.syncNoLock()
+}
+
+// Compact rewrites the log with only the live records right away. The copy
+// runs without the storage locked, writes go on meanwhile.
+func (this *FileStorage[K]) Compact() error {
+	this.compactLocker.Lock()
+	defer this.compactLocker.Unlock()
+	return this.compactLog()
+}
+
+func (this *FileStorage[K]) Close() error {
+	this.locker.Lock()
+	if this.file == nil || this.closing {
+		this.locker.Unlock()
+		return nil
+	}
+	this.closing = true
+	this.locker.Unlock()
+	close(this.done)
+	this.group.Wait()
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	err := this.syncNoLock()
+	if closeErr := this.file.Close(); err == nil {
+		err = closeErr
+	}
+	this.file = nil
+	return err
+}
+
+func (this *FileStorage[K]) read(key K) ([]byte, error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	position, ok := this.index[key]
+	if !ok || this.file == nil {
+		return nil, nil
+	}
+	data := make([]byte, position.length)
+	if _, err := this.file.ReadAt(data, position.offset); err != nil {
+		this.err = errors.Wrap(err, "read record")
+		return nil, this.err
+	}
+	return data, nil
+}
+
+func (this *FileStorage[K]) write(op byte, key K, data []byte) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if this.file == nil {
+		this.err = errors.New("file storage is closed")
+		return this.err
+	}
+	encodedKey, err := json.Marshal(key)
+	if err != nil {
+		this.err = errors.Wrap(err, "encode key")
+		return this.err
+	}
+	if len(encodedKey) > maxRecordKey || len(data) > maxRecordData {
+		this.err = errors.New("record too large")
+		return this.err
+	}
+	record := encodeRecord(op, encodedKey, data)
+	if _, err := this.file.WriteAt(record, this.size); err != nil {
+		this.err = errors.Wrap(err, "append record")
+		return this.err
+	}
+	this.apply(op, key, this.size, int64(len(encodedKey)), int64(len(data)))
+	this.size += int64(len(record))
+	this.dirty = true
+	if this.options.Sync == SYNC_ALWAYS {
+		if err := this.syncNoLock(); err != nil {
+			this.err = err
+			return err
+		}
+	}
+	if this.size >= this.options.CompactMinSize && float64(this.size-this.live) > float64(this.size)*this.options.CompactRatio {
+		select {
+		case this.compact <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// apply updates the index for a record starting at offset.
+func (this *FileStorage[K]) apply(op byte, key K, offset int64, keyLength int64, dataLength int64) {
+	order := this.next
+	if old, ok := this.index[key]; ok {
+		this.live -= old.record
+		delete(this.index, key)
+		order = old.order
+	}
+	if op == recordDelete {
+		return
+	}
+	if order == this.next {
+		this.next++
+	}
+	position := filePosition{
+		offset: offset + recordHeader + keyLength,
+		length: dataLength,
+		record: recordHeader + keyLength + dataLength,
+		order:  order,
+	}
+	this.index[key] = position
+	this.live += position.record
+}
+
+func (this *FileStorage[K]) open() error {
+	file, err := os.OpenFile(this.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return errors.Wrap(err, "open file storage")
+	}
+	this.file = file
+	this.index = make(map[K]filePosition)
+	this.size = 0
+	this.live = 0
+	this.next = 0
+	if err := this.replayNoLock(); err != nil {
+		this.file = nil
+		file.Close()
+		return err
+	}
+	return nil
+}
+
+// replayNoLock rebuilds the index from the log. Only a torn write at the end
+// is cut off: a record that stops short at the end of the file, or the last
+// record failing its checksum, or zeros up to the end. Anything unreadable
+// that has data after it is an error and the file stays as it is.
+func (this *FileStorage[K]) replayNoLock() error {
+	info, err := this.file.Stat()
+	if err != nil {
+		return errors.Wrap(err, "open file storage")
+	}
+	end := info.Size()
+	reader := bufio.NewReader(io.NewSectionReader(this.file, 0, end))
+	for {
+		op, key, data, length, err := readRecord(reader)
+		if err == io.EOF {
+			return nil
+		}
+		torn := err == io.ErrUnexpectedEOF
+		if err == errCorruptRecord {
+			torn = this.size+length == end
+		} else if err == errCorruptHeader {
+			torn, err = zeroTail(this.file, this.size, end)
+			if err != nil {
+				return errors.Wrap(err, "open file storage")
+			}
+		}
+		if torn {
+			if err := this.file.Truncate(this.size); err != nil {
+				return errors.Wrap(err, "truncate file storage")
+			}
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "open file storage at offset %d", this.size)
+		}
+		var decoded K
+		if err := json.Unmarshal(key, &decoded); err != nil {
+			return errors.Wrapf(err, "decode key at offset %d", this.size)
+		}
+		this.apply(op, decoded, this.size, int64(len(key)), int64(len(data)))
+		this.size += length
+	}
+}
+
+// zeroTail reports whether the file holds only zeros from offset to end.
+func zeroTail(file *os.File, offset int64, end int64) (bool, error) {
+	reader := bufio.NewReader(io.NewSectionReader(file, offset, end-offset))
+	for {
+		b, err := reader.ReadByte()
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if b != 0 {
+			return false, nil
+		}
+	}
+}
+
+func (this *FileStorage[K]) background() {
+	defer this.group.Done()
+	var tick <-chan time.Time
+	if this.options.Sync == SYNC_INTERVAL {
+		ticker := time.NewTicker(this.options.SyncInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-this.done:
+			return
+		case <-tick:
+			this.locker.Lock()
+			if err := this.syncNoLock(); err != nil {
+				this.err = err
+			}
+			this.locker.Unlock()
+		case <-this.compact:
+			if err := this.Compact(); err != nil {
+				this.locker.Lock()
+				this.err = err
+				this.locker.Unlock()
+			}
+		}
+	}
+}
+
+func (this *FileStorage[K]) syncNoLock() error {
+	if !this.dirty || this.file == nil {
+		return nil
+	}
+	if err := this.file.Sync(); err != nil {
+		return errors.Wrap(err, "sync file storage")
+	}
+	this.dirty = false
+	return nil
+}
+
+// orderedNoLock returns the stored keys in the order they were first added.
+func (this *FileStorage[K]) orderedNoLock() []K {
+	keys := make([]K, 0, len(this.index))
+	for key := range this.index {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return this.index[keys[i]].order < this.index[keys[j]].order
+	})
+	return keys
+}
+
+// compactLog copies the live records of a snapshot of the index into a new
+// file without the lock. Then, locked, it appends what was written to the log
+// meanwhile, syncs the new file and renames it over the log, so a crash
+// leaves either the old or the new log in place.
+func (this *FileStorage[K]) compactLog() error {
+	this.locker.Lock()
+	if this.file == nil || this.size == this.live {
+		this.locker.Unlock()
+		return nil
+	}
+	source := this.file
+	start := this.size
+	keys := this.orderedNoLock()
+	positions := make([]filePosition, len(keys))
+	for index, key := range keys {
+		positions[index] = this.index[key]
+	}
+	this.locker.Unlock()
+
+	temp := this.path + ".compact"
+	file, err := os.OpenFile(temp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return errors.Wrap(err, "create compaction file")
+	}
+	fail := func(err error) error {
+		file.Close()
+		os.Remove(temp)
+		return errors.Wrap(err, "compact file storage")
+	}
+	writer := bufio.NewWriter(file)
+	index := make(map[K]filePosition, len(keys))
+	var size int64
+	// written in key order so a reopened log keeps it, the log is append only
+	// so the snapshot positions stay valid
+	for i, key := range keys {
+		position := positions[i]
+		data := make([]byte, position.length)
+		if _, err := source.ReadAt(data, position.offset); err != nil {
+			return fail(err)
+		}
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return fail(err)
+		}
+		record := encodeRecord(recordAdd, encodedKey, data)
+		if _, err := writer.Write(record); err != nil {
+			return fail(err)
+		}
+		index[key] = filePosition{
+			offset: size + recordHeader + int64(len(encodedKey)),
+			length: position.length,
+			record: int64(len(record)),
+			order:  position.order,
+		}
+		size += int64(len(record))
+	}
+	if err := writer.Flush(); err != nil {
+		return fail(err)
+	}
+	if err := file.Sync(); err != nil {
+		return fail(err)
+	}
+
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if this.file != source {
+		return fail(errors.New("file storage is closed"))
+	}
+	// the records appended since the snapshot are copied as they are
+	tail := make([]byte, this.size-start)
+	if _, err := source.ReadAt(tail, start); err != nil {
+		return fail(err)
+	}
+	if _, err := file.WriteAt(tail, size); err != nil {
+		return fail(err)
+	}
+	if err := file.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := os.Rename(temp, this.path); err != nil {
+		return fail(err)
+	}
+	if err := syncDir(filepath.Dir(this.path)); err != nil {
+		this.err = errors.Wrap(err, "compact file storage")
+	}
+	source.Close()
+	this.file = file
+	this.index = index
+	this.size = size
+	this.live = size
+	this.dirty = false
+	reader := bufio.NewReader(bytes.NewReader(tail))
+	for {
+		op, key, data, length, err := readRecord(reader)
+		if err != nil {
+			break
+		}
+		var decoded K
+		if json.Unmarshal(key, &decoded) != nil {
+			break
+		}
+		this.apply(op, decoded, this.size, int64(len(key)), int64(len(data)))
+		this.size += length
+	}
+	return nil
+}
+
+// syncDir makes a rename in dir durable.
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return file.Sync()
+}
+
+type checkedFileStorage[K comparable] struct {
+	storage *FileStorage[K]
+}
+
+func (this checkedFileStorage[K]) Add(key K, data []byte) error {
+	return this.storage.write(recordAdd, key, data)
+}
+
+func (this checkedFileStorage[K]) Update(key K, data []byte) error {
+	return this.storage.write(recordUpdate, key, data)
+}
+
+func (this checkedFileStorage[K]) Delete(key K) error {
+	return this.storage.write(recordDelete, key, nil)
+}
+
+func (this checkedFileStorage[K]) Get(key K) (interface{}, error) {
+	data, err := this.storage.read(key)
+	if data == nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (this checkedFileStorage[K]) Iterate(fn func(key K, data interface{}) bool) error {
+	return this.storage.Iterate(fn)
+}
+
+func encodeRecord(op byte, key []byte, data []byte) []byte {
+	record := make([]byte, recordHeader+len(key)+len(data))
+	record[4] = op
+	binary.BigEndian.PutUint32(record[5:], uint32(len(key)))
+	binary.BigEndian.PutUint32(record[9:], uint32(len(data)))
+	copy(record[recordHeader:], key)
+	copy(record[recordHeader+len(key):], data)
+	binary.BigEndian.PutUint32(record, crc32.ChecksumIEEE(record[4:]))
+	return record
+}
+
+// readRecord returns io.EOF at the end of the log and io.ErrUnexpectedEOF for
+// a record cut short. On errCorruptRecord length is still the record's size.
+func readRecord(reader *bufio.Reader) (op byte, key []byte, data []byte, length int64, err error) {
+	header := make([]byte, recordHeader)
+	if _, err = io.ReadFull(reader, header); err != nil {
+		return
+	}
+	op = header[4]
+	keyLength := binary.BigEndian.Uint32(header[5:])
+	dataLength := binary.BigEndian.Uint32(header[9:])
+	if op < recordAdd || op > recordDelete || keyLength > maxRecordKey || dataLength > maxRecordData {
+		err = errCorruptHeader
+		return
+	}
+	body := make([]byte, int(keyLength)+int(dataLength))
+	if _, err = io.ReadFull(reader, body); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+	length = int64(len(header) + len(body))
+	checksum := crc32.NewIEEE()
+	checksum.Write(header[4:])
+	checksum.Write(body)
+	if checksum.Sum32() != binary.BigEndian.Uint32(header) {
+		err = errCorruptRecord
+		return
+	}
+	key = body[:keyLength]
+	data = body[keyLength:]
+	return
+}
@@ -0,0 +1,223 @@
+package list
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func openTestStorage[K comparable](t *testing.T, path string) *FileStorage[K] {
+	t.Helper()
+	storage, err := OpenFileStorage[K](path, FileStorageOptions{Sync: SYNC_NONE})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storage
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Size()
+}
+
+func TestFileStorageRecord(t *testing.T) {
+	record := encodeRecord(recordUpdate, []byte(`"key"`), []byte("data"))
+	if len(record) != recordHeader+5+4 {
+		t.Fatal(len(record))
+	}
+	op, key, data, length, err := readRecord(bufio.NewReader(bytes.NewReader(record)))
+	if err != nil || op != recordUpdate || string(key) != `"key"` || string(data) != "data" || length != int64(len(record)) {
+		t.Fatal(op, string(key), string(data), length, err)
+	}
+	if _, _, _, _, err := readRecord(bufio.NewReader(bytes.NewReader(record[:len(record)-1]))); err != io.ErrUnexpectedEOF {
+		t.Fatal(err)
+	}
+	if _, _, _, _, err := readRecord(bufio.NewReader(bytes.NewReader(nil))); err != io.EOF {
+		t.Fatal(err)
+	}
+	record[len(record)-1] ^= 1
+	if _, _, _, length, err := readRecord(bufio.NewReader(bytes.NewReader(record))); err != errCorruptRecord || length != int64(len(record)) {
+		t.Fatal(err, length)
+	}
+	record[4] = 0
+	if _, _, _, _, err := readRecord(bufio.NewReader(bytes.NewReader(record))); err != errCorruptHeader {
+		t.Fatal(err)
+	}
+}
+
+func TestFileStorageReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	storage := openTestStorage[string](t, path)
+	storage.Add("a", []byte("1"))
+	storage.Add("b", []byte("2"))
+	storage.Add("c", []byte("3"))
+	storage.Update("a", []byte("4"))
+	storage.Delete("b")
+	if err := storage.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	storage = openTestStorage[string](t, path)
+	defer storage.Close()
+	if storage.Len() != 2 || string(storage.Get("a").([]byte)) != "4" || storage.Get("b") != nil {
+		t.Fatal(storage.Len(), storage.Get("a"), storage.Get("b"))
+	}
+	var keys []string
+	storage.Iterate(func(key string, data interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if fmt.Sprint(keys) != "[a c]" {
+		t.Fatal(keys)
+	}
+}
+
+func TestFileStorageTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	storage := openTestStorage[string](t, path)
+	storage.Add("a", []byte("1"))
+	storage.Add("b", []byte("2"))
+	storage.Close()
+	good := fileSize(t, path)
+
+	tails := map[string][]byte{
+		"short":    encodeRecord(recordAdd, []byte(`"c"`), []byte("3"))[:10],
+		"checksum": encodeRecord(recordAdd, []byte(`"c"`), []byte("3")),
+		"zeros":    make([]byte, 64),
+	}
+	tails["checksum"][recordHeader] ^= 1
+	for name, tail := range tails {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.Write(tail)
+		file.Close()
+
+		storage = openTestStorage[string](t, path)
+		if storage.Len() != 2 || string(storage.Get("b").([]byte)) != "2" {
+			t.Fatal(name, storage.Len())
+		}
+		storage.Close()
+		if size := fileSize(t, path); size != good {
+			t.Fatal(name, size, good)
+		}
+	}
+}
+
+func TestFileStorageCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	storage := openTestStorage[string](t, path)
+	storage.Add("a", []byte("1"))
+	storage.Add("b", []byte("2"))
+	storage.Add("c", []byte("3"))
+	storage.Close()
+	size := fileSize(t, path)
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the data byte of the second record
+	second := int64(len(encodeRecord(recordAdd, []byte(`"a"`), []byte("1"))))
+	file.WriteAt([]byte("x"), second+recordHeader+3)
+	file.Close()
+
+	if _, err := OpenFileStorage[string](path, FileStorageOptions{}); err == nil {
+		t.Fatal("corrupt record in the middle was accepted")
+	}
+	if fileSize(t, path) != size {
+		t.Fatal("corrupt log was truncated")
+	}
+}
+
+func TestFileStorageWrongKeyType(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	storage := openTestStorage[string](t, path)
+	storage.Add("a", []byte("1"))
+	storage.Close()
+	size := fileSize(t, path)
+
+	if _, err := OpenFileStorage[int](path, FileStorageOptions{}); err == nil {
+		t.Fatal("string keys were decoded as int")
+	}
+	if fileSize(t, path) != size {
+		t.Fatal("log was truncated")
+	}
+}
+
+func TestFileStorageTooLarge(t *testing.T) {
+	storage := openTestStorage[string](t, filepath.Join(t.TempDir(), "log"))
+	defer storage.Close()
+	if err := storage.Checked().Add(strings.Repeat("k", maxRecordKey), nil); err == nil {
+		t.Fatal("oversized key was written")
+	}
+	if storage.Len() != 0 {
+		t.Fatal(storage.Len())
+	}
+}
+
+func TestFileStorageCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	storage := openTestStorage[int](t, path)
+	for i := 0; i < 100; i++ {
+		storage.Add(i, []byte(fmt.Sprint(i)))
+	}
+	for i := 0; i < 100; i += 2 {
+		storage.Delete(i)
+	}
+	before := fileSize(t, path)
+	if err := storage.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if size := fileSize(t, path); size >= before {
+		t.Fatal(size, before)
+	}
+
+	// writes during a compaction end up in the new log
+	var group sync.WaitGroup
+	group.Add(1)
+	go func() {
+		defer group.Done()
+		for i := 100; i < 200; i++ {
+			storage.Add(i, []byte(fmt.Sprint(i)))
+			storage.Delete(i - 99)
+		}
+	}()
+	if err := storage.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	group.Wait()
+	if err := storage.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(storage *FileStorage[int]) {
+		t.Helper()
+		for i := 0; i < 200; i++ {
+			data := storage.Get(i)
+			want := i > 100
+			if want != (data != nil) {
+				t.Fatal(i, data)
+			}
+			if data != nil && string(data.([]byte)) != fmt.Sprint(i) {
+				t.Fatal(i, string(data.([]byte)))
+			}
+		}
+	}
+	check(storage)
+	storage.Close()
+	storage = openTestStorage[int](t, path)
+	defer storage.Close()
+	check(storage)
+}
@@ -0,0 +1,125 @@
+package list
+
+import (
+	"github.com/pkg/errors"
+	"sort"
+)
+
+// listIndex maps what extractor returns for each element to the elements.
+type listIndex[K comparable, V any] struct {
+	extractor func(value V) string
+	values    map[K]string
+	keys      map[string]map[K]*Component[K, V]
+}
+
+// AddIndex starts a secondary index called name over what extractor returns
+// for each value. It covers the elements already in the list and then follows
+// every add, update and delete. extractor runs with the list locked and must
+// not call back into it.
+func (this *List[K, V]) AddIndex(name string, extractor func(value V) string) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if extractor == nil {
+		return errors.New("extractor is nil")
+	}
+	if _, ok := this.indexes[name]; ok {
+		return errors.New("duplicate index")
+	}
+	if this.indexes == nil {
+		this.indexes = make(map[string]*listIndex[K, V])
+	}
+	index := &listIndex[K, V]{
+		extractor: extractor,
+		values:    make(map[K]string),
+		keys:      make(map[string]map[K]*Component[K, V]),
+	}
+	for item := this.head; item != nil; item = item.Next {
+		index.put(item)
+	}
+	this.indexes[name] = index
+	return nil
+}
+
+func (this *List[K, V]) DropIndex(name string) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	delete(this.indexes, name)
+}
+
+// FindBy returns the keys whose value the index maps to value, head first.
+func (this *List[K, V]) FindBy(index string, value string) ([]K, error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	target, ok := this.indexes[index]
+	if !ok {
+		return nil, errors.New("index not found")
+	}
+	items := make([]*Component[K, V], 0, len(target.keys[value]))
+	for _, item := range target.keys[value] {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].rank.index() < items[j].rank.index()
+	})
+	keys := make([]K, len(items))
+	for i, item := range items {
+		keys[i] = item.Key
+	}
+	return keys, nil
+}
+
+// indexNoLock files item under its current value in every index, it is called
+// whenever an element is linked or its value changes.
+func (this *List[K, V]) indexNoLock(item *Component[K, V]) {
+	for _, index := range this.indexes {
+		index.put(item)
+	}
+}
+
+func (this *List[K, V]) unindexNoLock(item *Component[K, V]) {
+	for _, index := range this.indexes {
+		index.remove(item.Key)
+	}
+}
+
+// reindexNoLock rebuilds every index from the list.
+func (this *List[K, V]) reindexNoLock() {
+	for _, index := range this.indexes {
+		index.values = make(map[K]string)
+		index.keys = make(map[string]map[K]*Component[K, V])
+		for item := this.head; item != nil; item = item.Next {
+			index.put(item)
+		}
+	}
+}
+
+func (this *listIndex[K, V]) put(item *Component[K, V]) {
+	value := this.extractor(item.Data)
+	if old, ok := this.values[item.Key]; ok {
+		if old == value && this.keys[old][item.Key] == item {
+			return
+		}
+		this.remove(item.Key)
+	}
+	this.values[item.Key] = value
+	keys, ok := this.keys[value]
+	if !ok {
+		keys = make(map[K]*Component[K, V])
+		this.keys[value] = keys
+	}
+	keys[item.Key] = item
+}
+
+func (this *listIndex[K, V]) remove(key K) {
+	old, ok := this.values[key]
+	if !ok {
+		return
+	}
+	delete(this.values, key)
+	if keys := this.keys[old]; keys != nil {
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(this.keys, old)
+		}
+	}
+}
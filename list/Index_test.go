@@ -0,0 +1,95 @@
+package list
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func parity(value int) string {
+	if value%2 == 0 {
+		return "even"
+	}
+	return "odd"
+}
+
+func findBy(t *testing.T, list *List[string, int], value string) string {
+	t.Helper()
+	keys, err := list.FindBy("parity", value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprint(keys)
+}
+
+func TestIndexFollowsList(t *testing.T) {
+	list := New[string, int]()
+	for index, key := range []string{"a", "b", "c", "d", "e"} {
+		list.AddLast(key, index)
+	}
+	if err := list.AddIndex("parity", parity); err != nil {
+		t.Fatal(err)
+	}
+	if list.AddIndex("parity", parity) == nil || list.AddIndex("nil", nil) == nil {
+		t.Fatal("bad index was accepted")
+	}
+	if _, err := list.FindBy("missing", "odd"); err == nil {
+		t.Fatal("missing index was searched")
+	}
+	if got := findBy(t, list, "even"); got != "[a c e]" {
+		t.Fatal(got)
+	}
+
+	list.MoveToFront("e")
+	list.MoveAfter("a", "c")
+	if got := findBy(t, list, "even"); got != "[e c a]" {
+		t.Fatal(got)
+	}
+	list.Update("b", 4)
+	list.AddFirst("f", 5)
+	list.Remove("c")
+	if got := findBy(t, list, "even"); got != "[e b a]" {
+		t.Fatal(got)
+	}
+	if got := findBy(t, list, "odd"); got != "[f d]" {
+		t.Fatal(got)
+	}
+
+	err := list.Batch(func(tx *ListTx[string, int]) error {
+		tx.Update("d", 6)
+		tx.Remove("e")
+		tx.AddLast("g", 8)
+		return errors.New("roll back")
+	})
+	if err == nil {
+		t.Fatal("batch committed")
+	}
+	if got := findBy(t, list, "even"); got != "[e b a]" {
+		t.Fatal(got)
+	}
+	if got := findBy(t, list, "odd"); got != "[f d]" {
+		t.Fatal(got)
+	}
+
+	var buffer bytes.Buffer
+	source := New[string, int]()
+	source.AddLast("x", 1)
+	source.AddLast("y", 2)
+	source.AddLast("z", 3)
+	source.Snapshot(&buffer)
+	if err := list.Restore(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	if got := findBy(t, list, "odd"); got != "[x z]" {
+		t.Fatal(got)
+	}
+	if got := findBy(t, list, "even"); got != "[y]" {
+		t.Fatal(got)
+	}
+
+	list.DropIndex("parity")
+	if _, err := list.FindBy("parity", "odd"); err == nil {
+		t.Fatal("dropped index was searched")
+	}
+}
@@ -0,0 +1,155 @@
+package list
+
+// Iterate calls fn for every element head first until fn returns false. The
+// list stays locked throughout, fn must not call back into it.
+func (this *List[K, V]) Iterate(fn func(key K, value V) bool) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	for item := this.head; item != nil; item = item.Next {
+		if !fn(item.Key, item.Data) {
+			return
+		}
+	}
+}
+
+// IterateReverse is Iterate tail first.
+func (this *List[K, V]) IterateReverse(fn func(key K, value V) bool) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	for item := this.tail; item != nil; item = item.Prev {
+		if !fn(item.Key, item.Data) {
+			return
+		}
+	}
+}
+
+// Iterator walks a copy of the list taken when it was created, later changes
+// of the list do not affect it and it holds no lock.
+type Iterator[K comparable, V any] struct {
+	entries []Entry[K, V]
+	index   int
+	reverse bool
+}
+
+// Iterator returns a snapshot iterator, head first.
+func (this *List[K, V]) Iterator() *Iterator[K, V] {
+	return &Iterator[K, V]{entries: this.Slice(), index: -1}
+}
+
+// ReverseIterator returns a snapshot iterator, tail first.
+func (this *List[K, V]) ReverseIterator() *Iterator[K, V] {
+	entries := this.Slice()
+	return &Iterator[K, V]{entries: entries, index: len(entries), reverse: true}
+}
+
+// Next moves to the next element and reports whether there is one.
+func (this *Iterator[K, V]) Next() bool {
+	if this.reverse {
+		if this.index > 0 {
+			this.index--
+			return true
+		}
+		return false
+	}
+	if this.index < len(this.entries)-1 {
+		this.index++
+		return true
+	}
+	this.index = len(this.entries)
+	return false
+}
+
+func (this *Iterator[K, V]) Key() (key K) {
+	if this.index >= 0 && this.index < len(this.entries) {
+		key = this.entries[this.index].Key
+	}
+	return
+}
+
+func (this *Iterator[K, V]) Value() (value V) {
+	if this.index >= 0 && this.index < len(this.entries) {
+		value = this.entries[this.index].Data
+	}
+	return
+}
+
+// Len returns how many elements the snapshot holds.
+func (this *Iterator[K, V]) Len() int {
+	return len(this.entries)
+}
+
+// Cursor walks the live list one element per Next, locking it only inside
+// Next. When the element it stands on is removed or moved meanwhile, it goes
+// on with the neighbour that element had, so neither breaks a walk. Elements
+// added or moved ahead of it are seen where they land, the current one too,
+// an element moved behind it is not seen again.
+type Cursor[K comparable, V any] struct {
+	list    *List[K, V]
+	current *Component[K, V]
+	next    *Component[K, V] // what followed current when Next returned it
+	moved   uint64           // current.moved at that time
+	started bool
+	reverse bool
+	key     K
+	value   V
+}
+
+// Cursor returns a live cursor, head first.
+func (this *List[K, V]) Cursor() *Cursor[K, V] {
+	return &Cursor[K, V]{list: this}
+}
+
+// ReverseCursor returns a live cursor, tail first.
+func (this *List[K, V]) ReverseCursor() *Cursor[K, V] {
+	return &Cursor[K, V]{list: this, reverse: true}
+}
+
+// Next moves to the next element and reports whether there is one, Key and
+// Value are read at that moment.
+func (this *Cursor[K, V]) Next() bool {
+	this.list.locker.Lock()
+	defer this.list.locker.Unlock()
+	var item *Component[K, V]
+	if !this.started {
+		this.started = true
+		item = this.list.head
+		if this.reverse {
+			item = this.list.tail
+		}
+	} else if this.current != nil {
+		item = this.step(this.current)
+		if this.list.container[this.current.Key] == this.current && this.current.moved != this.moved {
+			item = this.next
+		}
+		// a removed element keeps the links it had, follow them to the
+		// first one that is still in the list
+		for item != nil && this.list.container[item.Key] != item {
+			item = this.step(item)
+		}
+	}
+	this.current = item
+	if item == nil {
+		var key K
+		var value V
+		this.key, this.value = key, value
+		return false
+	}
+	this.key, this.value = item.Key, item.Data
+	this.next, this.moved = this.step(item), item.moved
+	return true
+}
+
+func (this *Cursor[K, V]) Key() K {
+	return this.key
+}
+
+func (this *Cursor[K, V]) Value() V {
+	return this.value
+}
+
+func (this *Cursor[K, V]) step(item *Component[K, V]) *Component[K, V] {
+	if this.reverse {
+		return item.Prev
+	}
+	return item.Next
+}
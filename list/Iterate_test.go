@@ -0,0 +1,86 @@
+package list
+
+import (
+	"fmt"
+	"testing"
+)
+
+func newABCD() *List[string, int] {
+	list := New[string, int]()
+	for index, key := range []string{"a", "b", "c", "d"} {
+		list.AddLast(key, index)
+	}
+	return list
+}
+
+// walk runs cursor to the end, calling change once it stands on at.
+func walk(cursor *Cursor[string, int], at string, change func()) string {
+	var keys []string
+	for cursor.Next() {
+		keys = append(keys, cursor.Key())
+		if cursor.Key() == at && change != nil {
+			change()
+			change = nil
+		}
+	}
+	return fmt.Sprint(keys)
+}
+
+func TestCursorChanges(t *testing.T) {
+	cases := []struct {
+		name   string
+		at     string
+		change func(list *List[string, int])
+		want   string
+	}{
+		{"none", "", nil, "[a b c d]"},
+		{"move current to front", "c", func(list *List[string, int]) { list.MoveToFront("c") }, "[a b c d]"},
+		{"move current ahead", "b", func(list *List[string, int]) { list.MoveToBack("b") }, "[a b c d b]"},
+		{"move next behind", "b", func(list *List[string, int]) { list.MoveToFront("c") }, "[a b d]"},
+		{"move other ahead", "b", func(list *List[string, int]) { list.MoveAfter("a", "c") }, "[a b c a d]"},
+		{"remove current", "b", func(list *List[string, int]) { list.Remove("b") }, "[a b c d]"},
+		{"remove current and next", "b", func(list *List[string, int]) { list.Remove("b"); list.Remove("c") }, "[a b d]"},
+		{"move current then remove next", "b", func(list *List[string, int]) { list.MoveToFront("b"); list.Remove("c") }, "[a b d]"},
+		{"add ahead", "b", func(list *List[string, int]) { list.AddLast("e", 4) }, "[a b c d e]"},
+		{"add behind", "b", func(list *List[string, int]) { list.AddFirst("e", 4) }, "[a b c d]"},
+	}
+	for _, test := range cases {
+		list := newABCD()
+		var change func()
+		if test.change != nil {
+			change = func() { test.change(list) }
+		}
+		if got := walk(list.Cursor(), test.at, change); got != test.want {
+			t.Fatal(test.name, got)
+		}
+	}
+
+	list := newABCD()
+	if got := walk(list.ReverseCursor(), "b", func() { list.MoveToBack("b") }); got != "[d c b a]" {
+		t.Fatal("reverse", got)
+	}
+}
+
+func TestIteratorIsSnapshot(t *testing.T) {
+	list := newABCD()
+	iterator := list.Iterator()
+	reverse := list.ReverseIterator()
+	list.MoveToFront("c")
+	list.Remove("a")
+	list.AddLast("e", 4)
+	var keys, values []string
+	for iterator.Next() {
+		keys = append(keys, iterator.Key())
+		values = append(values, fmt.Sprint(iterator.Value()))
+	}
+	if fmt.Sprint(keys, values) != "[a b c d] [0 1 2 3]" || iterator.Len() != 4 || iterator.Key() != "" {
+		t.Fatal(keys, values)
+	}
+	keys = nil
+	for reverse.Next() {
+		keys = append(keys, reverse.Key())
+	}
+	if fmt.Sprint(keys) != "[d c b a]" {
+		t.Fatal(keys)
+	}
+}
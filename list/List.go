@@ -0,0 +1,1013 @@
+package list
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type EVENT int
+
+const (
+	ADD EVENT = iota
+	UPDATE
+	DELETE
+	SORT
+	EVICT
+	EXPIRE
+	MOVE
+	RESTORE
+	BATCH
+)
+
+type Component[K comparable, V any] struct {
+	Key   K                `json:"key"`
+	Data  V                `json:"data"`
+	Next  *Component[K, V] `json:"-"`
+	Prev  *Component[K, V] `json:"-"`
+	rank  *orderNode[K, V]
+	moved uint64 // when it was last moved, see Cursor
+}
+
+// Event describes one change of a list. Component is a copy of the element
+// taken when the change happened, its Next/Prev are nil and the neighbours are
+// given by key instead. For DELETE, EVICT and EXPIRE they are the neighbours
+// the element had before it left.
+type Event[K comparable, V any] struct {
+	*Component[K, V]
+	Event    EVENT
+	Old      V         // value before an UPDATE
+	Sequence uint64    // increases by one with every event of the list
+	Time     time.Time // when the list applied the change
+	PrevKey  K
+	HasPrev  bool
+	NextKey  K
+	HasNext  bool
+	Order    []K // every key head first, only set for SORT, RESTORE and BATCH
+	// Batch holds the changes of a BATCH in the order they were made. They
+	// share its Sequence, Component is a copy of the first one.
+	Batch []Event[K, V]
+}
+
+type Storage[K comparable] interface {
+	Add(key K, data []byte)
+	Delete(key K)
+	Update(key K, data []byte)
+	Get(key K) interface{}
+}
+
+// OrderedStorage is implemented by storages that also keep the element order.
+// Reorder receives every key of the list, head first.
+type OrderedStorage[K comparable] interface {
+	Reorder(keys []K)
+}
+
+// PositionStorage is implemented by storages that keep the element order and
+// can reposition a single key. Move places key directly behind after, or at
+// the front when first is true. The Move functions prefer it to Reorder.
+type PositionStorage[K comparable] interface {
+	Move(key K, after K, first bool) error
+}
+
+// IterableStorage is implemented by storages that can list what they hold.
+// Iterate calls fn with every key and what Get would return for it, in stored
+// order, until fn returns false.
+type IterableStorage[K comparable] interface {
+	Iterate(fn func(key K, data interface{}) bool) error
+}
+
+// List is a keyed doubly linked list. Every element is reachable by key in
+// O(1) and keeps its insertion position, mutations are broadcast on the event
+// channel and mirrored to the optional Storage. A storage write happens before
+// the list changes, see SetFailurePolicy for what a failed write does.
+type List[K comparable, V any] struct {
+	container    map[K]*Component[K, V]
+	head         *Component[K, V]
+	tail         *Component[K, V]
+	order        orderTree[K, V]
+	reorder      bool
+	indexes      map[string]*listIndex[K, V]
+	locker       sync.Mutex
+	listener     *Subscription[K, V]
+	subscribers  []*Subscription[K, V]
+	sequence     uint64
+	moves        uint64
+	storage      CheckedStorage[K]
+	codec        Codec
+	failure      FAILURE
+	retries      int
+	backoff      time.Duration
+	repairs      writeQueue[K]
+	writeBehind  bool
+	behind       writeQueue[K]
+	inflight     writeQueue[K]
+	flushing     bool
+	flushSignal  chan struct{}
+	flushLocker  sync.Mutex
+	capacity     int
+	bound        int
+	consumers    waitQueue
+	producers    waitQueue
+	purgeOnEvict bool
+	expires      map[K]*deadline[K]
+	deadlines    deadlineHeap[K]
+	expireKeep   bool
+	reaping      bool
+	repairing    bool
+	stop         chan struct{}
+	workers      sync.WaitGroup
+	snapshot     SNAPSHOT
+	LastProcess  string
+
+	storageErrorHandler func(err *StorageError[K])
+	loads               map[K]*storageLoad
+	missTTL             time.Duration
+	misses              map[K]time.Time
+	missSweep           int
+	behindBatch         int
+}
+
+func New[K comparable, V any]() *List[K, V] {
+	return &List[K, V]{container: make(map[K]*Component[K, V])}
+}
+
+func NewWithStorage[K comparable, V any](storage Storage[K]) *List[K, V] {
+	return &List[K, V]{container: make(map[K]*Component[K, V]), storage: Checked(storage)}
+}
+
+// NewLRU creates a list that works as a least recently used cache in front of
+// storage. Find and updates move the element they hit to the front, AddLast
+// and AddLastOrUpdate insert there too, and every insert that grows the list
+// beyond capacity evicts from the tail. Evicted elements stay in storage
+// unless SetPurgeOnEvict(true) is called.
+func NewLRU[K comparable, V any](capacity int, storage Storage[K]) *List[K, V] {
+	return &List[K, V]{container: make(map[K]*Component[K, V]), storage: Checked(storage), capacity: capacity}
+}
+
+// SetPurgeOnEvict decides whether evicted elements are deleted from storage too.
+func (this *List[K, V]) SetPurgeOnEvict(purge bool) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	this.purgeOnEvict = purge
+}
+
+func (this *List[K, V]) Capacity() int {
+	return this.capacity
+}
+
+// CreateEventListener sets up the list's default listener, a subscription
+// without filter. Further consumers should use Subscribe.
+func (this *List[K, V]) CreateEventListener(buffer int) (int, chan Event[K, V]) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if this.listener == nil {
+		this.listener = this.subscribeNoLock(make(chan Event[K, V], buffer), nil)
+	}
+	return cap(this.listener.channel), this.listener.channel
+}
+
+func (this *List[K, V]) SetEventListener(listener chan Event[K, V]) bool {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if this.listener == nil {
+		this.listener = this.subscribeNoLock(listener, nil)
+		return true
+	} else {
+		return false
+	}
+}
+
+func (this *List[K, V]) AddLast(key K, data V) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	_, err := this.addLastNoLock(key, data)
+	return err
+}
+
+func (this *List[K, V]) addLastNoLock(key K, data V) (*Component[K, V], error) {
+	if _, ok := this.container[key]; ok {
+		return nil, errors.New("duplicate key")
+	} else {
+		payload, err := this.encodeNoLock(data)
+		if err != nil {
+			return nil, err
+		}
+		if err := this.storageAdd(key, payload); err != nil {
+			return nil, err
+		}
+		temp := &Component[K, V]{Data: data, Key: key}
+		this.linkRecentNoLock(temp)
+		this.broadcastEvent(this.newEventNoLock(ADD, temp))
+		this.evictNoLock(temp)
+		return temp, nil
+	}
+}
+
+func (this *List[K, V]) AddLastOrUpdateFromStorage(key K, data V, fromStorage bool) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if fromStorage {
+		this.forgetNoLock(key)
+	}
+	this.addLastOrUpdateNoLock(key, data, fromStorage)
+}
+
+func (this *List[K, V]) AddLastOrUpdate(key K, data V) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return this.addLastOrUpdateNoLock(key, data, false)
+}
+
+func (this *List[K, V]) DeleteFromStorage(target K, fromStorage bool) (element V) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if fromStorage {
+		this.forgetNoLock(target)
+	}
+	element, _ = this.deleteNoLock(target, fromStorage)
+	return
+}
+
+// RemoveWithError is Remove reporting a failed storage delete. Under ROLLBACK
+// and RETRY the element is then still in the list.
+func (this *List[K, V]) RemoveWithError(target K) (element V, err error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return this.deleteNoLock(target, false)
+}
+
+func (this *List[K, V]) deleteNoLock(target K, fromStorage bool) (element V, err error) {
+	name := keyName(target)
+	this.LastProcess = "DeleteFromStorage " + name
+	if data, ok := this.container[target]; ok {
+		if !fromStorage {
+			if err = this.storageDelete(data.Key); err != nil {
+				return
+			}
+		}
+		this.LastProcess = "DeleteFromStorage " + name + " OK 1"
+		this.unlinkNoLock(data)
+		element = data.Data
+		this.LastProcess = "DeleteFromStorage " + name + " OK 3"
+		this.broadcastEvent(this.newEventNoLock(DELETE, data))
+		this.LastProcess = "DeleteFromStorage " + name + " OK DONE"
+		return
+	} else {
+		this.LastProcess = "DeleteFromStorage " + name + " else 4"
+		if !fromStorage {
+			err = this.storageDelete(target)
+		}
+		this.LastProcess = "DeleteFromStorage " + name + " else DONE"
+		return
+	}
+}
+
+// keyName is key as LastProcess shows it, a string key is used as it is.
+func keyName[K comparable](key K) string {
+	switch key := any(key).(type) {
+	case string:
+		return key
+	case int:
+		return strconv.Itoa(key)
+	}
+	return fmt.Sprint(key)
+}
+
+func (this *List[K, V]) AddLastOnExistIgnore(key K, data V) bool {
+	added, _ := this.AddLastOnExistIgnoreWithError(key, data)
+	return added
+}
+
+// AddLastOnExistIgnoreWithError tells an existing key, false and no error,
+// apart from a failed encode or storage write.
+func (this *List[K, V]) AddLastOnExistIgnoreWithError(key K, data V) (bool, error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	name := keyName(key)
+	this.LastProcess = "AddLastOnExistIgnore " + name
+	if _, ok := this.container[key]; ok {
+		return false, nil
+	} else {
+		payload, err := this.encodeNoLock(data)
+		if err != nil {
+			return false, err
+		}
+		if err := this.storageAdd(key, payload); err != nil {
+			return false, err
+		}
+		this.LastProcess = "AddLastOnExistIgnore " + name + " NOT 1"
+		temp := &Component[K, V]{Data: data, Key: key}
+		this.linkRecentNoLock(temp)
+		this.LastProcess = "AddLastOnExistIgnore " + name + " NOT 1.1 " + "subscribers " + strconv.Itoa(len(this.subscribers))
+		this.broadcastEvent(this.newEventNoLock(ADD, temp))
+		this.LastProcess = "AddLastOnExistIgnore " + name + " NOT 2"
+		this.evictNoLock(temp)
+		this.LastProcess = "AddLastOnExistIgnore " + name + " NOT done"
+		return true, nil
+	}
+}
+
+func (this *List[K, V]) AddFirst(key K, data V) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if _, ok := this.container[key]; ok {
+		return errors.New("duplicate key")
+	} else {
+		payload, err := this.encodeNoLock(data)
+		if err != nil {
+			return err
+		}
+		if err := this.storageAdd(key, payload); err != nil {
+			return err
+		}
+		temp := &Component[K, V]{Data: data, Key: key}
+		this.linkFirstNoLock(temp)
+		this.broadcastEvent(this.newEventNoLock(ADD, temp))
+		this.evictNoLock(temp)
+		return nil
+	}
+}
+
+func (this *List[K, V]) AddAfter(key K, data V, target K) (bool, error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if _, ok := this.container[key]; ok {
+		return false, errors.New("duplicate key")
+	} else {
+		if target, ok := this.container[target]; ok {
+			payload, err := this.encodeNoLock(data)
+			if err != nil {
+				return false, err
+			}
+			if err := this.storageAdd(key, payload); err != nil {
+				return false, err
+			}
+			temp := &Component[K, V]{Data: data, Key: key}
+			this.linkAfterNoLock(temp, target)
+			this.broadcastEvent(this.newEventNoLock(ADD, temp))
+			this.evictNoLock(temp)
+			return true, nil
+		} else {
+			return false, errors.New("target not found")
+		}
+	}
+}
+
+// AddWithCondition inserts key before the first element compare accepts, or
+// last when there is none. result tells whether it went before an element.
+// The scan and the insert happen under one lock, compare must not call back
+// into the list. SortedList keeps an order in O(log n) per insert.
+func (this *List[K, V]) AddWithCondition(key K, data V, compare func(data V) bool) (result bool, err error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if _, ok := this.container[key]; ok {
+		return false, errors.New("duplicate key")
+	}
+	var target *Component[K, V]
+	for item := this.head; item != nil; item = item.Next {
+		if compare(item.Data) {
+			target = item
+			break
+		}
+	}
+	payload, err := this.encodeNoLock(data)
+	if err != nil {
+		return false, err
+	}
+	if err := this.storageAdd(key, payload); err != nil {
+		return false, err
+	}
+	temp := &Component[K, V]{Data: data, Key: key}
+	if target == nil {
+		this.linkLastNoLock(temp)
+	} else {
+		this.linkBeforeNoLock(temp, target)
+		result = true
+	}
+	this.broadcastEvent(this.newEventNoLock(ADD, temp))
+	this.evictNoLock(temp)
+	return
+}
+
+func (this *List[K, V]) AddBefore(key K, data V, target K) (bool, error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if _, ok := this.container[key]; ok {
+		return false, errors.New("duplicate key")
+	} else {
+		if target, ok := this.container[target]; ok {
+			payload, err := this.encodeNoLock(data)
+			if err != nil {
+				return false, err
+			}
+			if err := this.storageAdd(key, payload); err != nil {
+				return false, err
+			}
+			temp := &Component[K, V]{Data: data, Key: key}
+			this.linkBeforeNoLock(temp, target)
+			this.broadcastEvent(this.newEventNoLock(ADD, temp))
+			this.evictNoLock(temp)
+			return true, nil
+		} else {
+			return false, errors.New("target not found")
+		}
+	}
+}
+
+func (this *List[K, V]) RemoveFirst() (key K, element V) {
+	key, element, _ = this.RemoveFirstWithError()
+	return
+}
+
+// RemoveFirstWithError is RemoveFirst reporting a failed storage delete, like
+// RemoveWithError. The same goes for the other WithError removes.
+func (this *List[K, V]) RemoveFirstWithError() (key K, element V, err error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if this.head != nil {
+		return this.removeNoLock(this.head)
+	} else {
+		return
+	}
+}
+
+func (this *List[K, V]) RemoveLast() (key K, element V) {
+	key, element, _ = this.RemoveLastWithError()
+	return
+}
+
+func (this *List[K, V]) RemoveLastWithError() (key K, element V, err error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if this.tail != nil {
+		return this.removeNoLock(this.tail)
+	} else {
+		return
+	}
+}
+
+func (this *List[K, V]) RemoveAfter(target K) (key K, element V) {
+	key, element, _ = this.RemoveAfterWithError(target)
+	return
+}
+
+func (this *List[K, V]) RemoveAfterWithError(target K) (key K, element V, err error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if target, ok := this.container[target]; ok && target.Next != nil {
+		return this.removeNoLock(target.Next)
+	} else {
+		return
+	}
+}
+
+func (this *List[K, V]) RemoveBefore(target K) (key K, element V) {
+	key, element, _ = this.RemoveBeforeWithError(target)
+	return
+}
+
+func (this *List[K, V]) RemoveBeforeWithError(target K) (key K, element V, err error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if target, ok := this.container[target]; ok && target.Prev != nil {
+		return this.removeNoLock(target.Prev)
+	} else {
+		return
+	}
+}
+
+func (this *List[K, V]) removeNoLock(data *Component[K, V]) (key K, element V, err error) {
+	if err = this.storageDelete(data.Key); err != nil {
+		return
+	}
+	this.unlinkNoLock(data)
+	key = data.Key
+	element = data.Data
+	this.broadcastEvent(this.newEventNoLock(DELETE, data))
+	return
+}
+
+func (this *List[K, V]) addLastOrUpdateNoLock(key K, data V, fromStorage bool) error {
+	name := keyName(key)
+	this.LastProcess = "AddLastOrUpdateFromStorage " + name
+	var payload []byte
+	if !fromStorage {
+		var err error
+		if payload, err = this.encodeNoLock(data); err != nil {
+			return err
+		}
+	}
+	if temp, ok := this.container[key]; ok {
+		if !fromStorage {
+			if err := this.storageUpdate(key, payload); err != nil {
+				return err
+			}
+		}
+		old := temp.Data
+		temp.Data = data
+		this.indexNoLock(temp)
+		this.LastProcess = "AddLastOrUpdateFromStorage " + name + " OK 1"
+		this.broadcastUpdateNoLock(temp, old)
+		if !fromStorage {
+			this.touchNoLock(temp)
+		}
+		this.LastProcess = "AddLastOrUpdateFromStorage " + name + " OK 2"
+		this.LastProcess = "AddLastOrUpdateFromStorage " + name + " OK Done"
+	} else {
+		if !fromStorage {
+			if err := this.storageAdd(key, payload); err != nil {
+				return err
+			}
+		}
+		temp := &Component[K, V]{Data: data, Key: key}
+		this.LastProcess = "AddLastOrUpdateFromStorage " + name + " ELSE 1"
+		if fromStorage {
+			this.linkLastNoLock(temp)
+		} else {
+			this.linkRecentNoLock(temp)
+		}
+		this.LastProcess = "AddLastOrUpdateFromStorage " + name + " ELSE 2"
+		this.broadcastEvent(this.newEventNoLock(ADD, temp))
+		this.LastProcess = "AddLastOrUpdateFromStorage " + name + " ELSE 3"
+		this.evictNoLock(temp)
+		this.LastProcess = "AddLastOrUpdateFromStorage " + name + " ELSE done"
+	}
+	return nil
+}
+
+// linkRecentNoLock appends temp, an LRU list puts it at the front instead,
+// the end that is evicted last.
+func (this *List[K, V]) linkRecentNoLock(temp *Component[K, V]) {
+	if this.capacity > 0 {
+		this.linkFirstNoLock(temp)
+	} else {
+		this.linkLastNoLock(temp)
+	}
+}
+
+// touchNoLock counts a use of data, an LRU list moves it to the front.
+func (this *List[K, V]) touchNoLock(data *Component[K, V]) {
+	if this.capacity > 0 {
+		this.moveToFrontNoLock(data)
+	}
+}
+
+// evictNoLock drops elements from the tail while an LRU list is over capacity.
+// The element that was just inserted is never the one evicted.
+func (this *List[K, V]) evictNoLock(inserted *Component[K, V]) {
+	this.trimNoLock(inserted, this.purgeOnEvict)
+}
+
+// trimNoLock is evictNoLock, it deletes the evicted keys from storage only
+// when purge is set.
+func (this *List[K, V]) trimNoLock(inserted *Component[K, V], purge bool) {
+	if this.capacity <= 0 {
+		return
+	}
+	for len(this.container) > this.capacity {
+		data := this.tail
+		if data == inserted {
+			data = data.Prev
+		}
+		if purge {
+			this.purgeNoLock(data.Key)
+		}
+		this.unlinkNoLock(data)
+		this.broadcastEvent(this.newEventNoLock(EVICT, data))
+	}
+}
+
+func (this *List[K, V]) linkLastNoLock(temp *Component[K, V]) {
+	this.container[temp.Key] = temp
+	this.indexNoLock(temp)
+	this.signalNoLock()
+	this.order.insert(orderSize(this.order.root), temp)
+	if this.head == nil {
+		this.head = temp
+		this.tail = temp
+	} else {
+		this.tail.Next = temp
+		temp.Prev = this.tail
+		this.tail = temp
+	}
+}
+
+func (this *List[K, V]) linkFirstNoLock(temp *Component[K, V]) {
+	this.container[temp.Key] = temp
+	this.indexNoLock(temp)
+	this.signalNoLock()
+	this.order.insert(0, temp)
+	if this.head == nil {
+		this.head = temp
+		this.tail = temp
+	} else {
+		this.head.Prev = temp
+		temp.Next = this.head
+		this.head = temp
+	}
+}
+
+func (this *List[K, V]) linkAfterNoLock(temp *Component[K, V], target *Component[K, V]) {
+	this.container[temp.Key] = temp
+	this.indexNoLock(temp)
+	this.signalNoLock()
+	this.order.insert(target.rank.index()+1, temp)
+	temp.Prev = target
+	temp.Next = target.Next
+	if target.Next == nil {
+		this.tail = temp
+	} else {
+		target.Next.Prev = temp
+	}
+	target.Next = temp
+}
+
+func (this *List[K, V]) linkBeforeNoLock(temp *Component[K, V], target *Component[K, V]) {
+	this.container[temp.Key] = temp
+	this.indexNoLock(temp)
+	this.signalNoLock()
+	this.order.insert(target.rank.index(), temp)
+	temp.Next = target
+	temp.Prev = target.Prev
+	if target.Prev == nil {
+		this.head = temp
+	} else {
+		target.Prev.Next = temp
+	}
+	target.Prev = temp
+}
+
+// unlinkNoLock removes data from the chain, the container, the indexes and
+// the expiry index. The component itself keeps its stale Next/Prev pointers.
+func (this *List[K, V]) unlinkNoLock(data *Component[K, V]) {
+	this.detachNoLock(data)
+	this.unindexNoLock(data)
+	delete(this.container, data.Key)
+	this.clearDeadlineNoLock(data.Key)
+	this.signalNoLock()
+}
+
+// detachNoLock only relinks the neighbours of data, it stays in the container.
+func (this *List[K, V]) detachNoLock(data *Component[K, V]) {
+	this.order.remove(data)
+	if data.Prev == nil {
+		this.head = data.Next
+	} else {
+		data.Prev.Next = data.Next
+	}
+	if data.Next == nil {
+		this.tail = data.Prev
+	} else {
+		data.Next.Prev = data.Prev
+	}
+}
+
+// SetCodec chooses how values are serialized for storage, JSONCodec when it
+// is never called.
+func (this *List[K, V]) SetCodec(codec Codec) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	this.codec = codec
+}
+
+func (this *List[K, V]) codecNoLock() Codec {
+	if this.codec == nil {
+		return JSONCodec{}
+	}
+	return this.codec
+}
+
+// encodeNoLock serializes data for storage before the list is changed, so an
+// encoding error leaves both untouched. Without storage there is nothing to do.
+func (this *List[K, V]) encodeNoLock(data V) ([]byte, error) {
+	if this.storage == nil {
+		return nil, nil
+	}
+	payload, err := this.codecNoLock().Encode(&data)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode value")
+	}
+	return payload, nil
+}
+
+func (this *List[K, V]) storageAdd(key K, payload []byte) error {
+	return this.writeNoLock(StorageWrite[K]{Op: "add", Key: key, Data: payload})
+}
+
+func (this *List[K, V]) storageUpdate(key K, payload []byte) error {
+	return this.writeNoLock(StorageWrite[K]{Op: "update", Key: key, Data: payload})
+}
+
+func (this *List[K, V]) storageDelete(key K) error {
+	return this.writeNoLock(StorageWrite[K]{Op: "delete", Key: key})
+}
+
+// purgeNoLock deletes key from storage for an element the list drops on its
+// own, eviction or expiry. The element goes whatever the outcome, a failure
+// reaches the storage error handler, and the repair queue under QUEUE, through
+// writeNoLock.
+func (this *List[K, V]) purgeNoLock(key K) {
+	_ = this.storageDelete(key)
+}
+
+// storageValue converts what Storage.Get returned into V. A []byte payload is
+// decoded with the codec, only an untyped list without a codec takes it as it
+// is, which is how those always behaved. Anything else has to already be a V.
+func (this *List[K, V]) storageValue(raw interface{}) (value V, ok bool) {
+	if raw == nil {
+		return
+	}
+	if payload, isBytes := raw.([]byte); isBytes {
+		if _, untyped := interface{}(&value).(*interface{}); !untyped || this.codec != nil {
+			ok = this.codecNoLock().Decode(payload, &value) == nil
+			return
+		}
+	}
+	value, ok = raw.(V)
+	return
+}
+
+func (this *List[K, V]) newEventNoLock(kind EVENT, data *Component[K, V]) Event[K, V] {
+	this.sequence++
+	event := eventOf(kind, data)
+	event.Sequence = this.sequence
+	return event
+}
+
+// eventOf describes data as it is now, without taking a sequence number.
+func eventOf[K comparable, V any](kind EVENT, data *Component[K, V]) Event[K, V] {
+	event := Event[K, V]{
+		Component: &Component[K, V]{
+			Key:  data.Key,
+			Data: data.Data,
+		},
+		Event: kind,
+		Time:  time.Now(),
+	}
+	if data.Prev != nil {
+		event.PrevKey, event.HasPrev = data.Prev.Key, true
+	}
+	if data.Next != nil {
+		event.NextKey, event.HasNext = data.Next.Key, true
+	}
+	return event
+}
+
+func (this *List[K, V]) broadcastUpdateNoLock(data *Component[K, V], old V) {
+	event := this.newEventNoLock(UPDATE, data)
+	event.Old = old
+	this.broadcastEvent(event)
+}
+
+func (this *List[K, V]) broadcastEvent(event Event[K, V]) {
+	for _, subscriber := range this.subscribers {
+		subscriber.deliver(event)
+	}
+}
+
+// Find returns the element of target. A miss reads through to storage, see
+// loadNoLock, and appends what it found.
+func (this *List[K, V]) Find(target K) (element V) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	element, _ = this.findNoLock(target)
+	return
+}
+
+// findNoLock is Find, ok tells whether target was found. The list may be
+// unlocked meanwhile, but it is locked from the last look at the container on.
+func (this *List[K, V]) findNoLock(target K) (element V, ok bool) {
+	for {
+		if data, ok := this.container[target]; ok && this.expiredNoLock(target, time.Now()) {
+			this.expireNoLock(data)
+		}
+		if data, ok := this.container[target]; ok {
+			this.touchNoLock(data)
+			return data.Data, true
+		}
+		raw, owner, fresh := this.loadNoLock(target)
+		if !fresh {
+			// the list changed while it was unlocked, look again
+			continue
+		}
+		if _, exist := this.container[target]; exist || (raw != nil && !owner) {
+			continue
+		}
+		if value, ok := this.storageValue(raw); ok {
+			this.addLastOrUpdateNoLock(target, value, true)
+			this.touchNoLock(this.container[target])
+			return value, true
+		}
+		return
+	}
+}
+
+// LoadFromStorage appends every stored key that is not in the list yet, in
+// stored order, and broadcasts ADD for each. Nothing is written back. An LRU
+// list stops loading once it is full. The storage has to be an
+// IterableStorage.
+func (this *List[K, V]) LoadFromStorage() (loaded int, err error) {
+	this.locker.Lock()
+	storage := this.storage
+	this.locker.Unlock()
+	iterable, ok := storage.(IterableStorage[K])
+	if !ok {
+		return 0, errors.New("storage cannot iterate")
+	}
+	// read everything first so the list is not locked during storage calls
+	var keys []K
+	var raws []interface{}
+	err = iterable.Iterate(func(key K, data interface{}) bool {
+		keys = append(keys, key)
+		raws = append(raws, data)
+		return true
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "iterate storage")
+	}
+
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	for index, key := range keys {
+		if this.capacity > 0 && len(this.container) >= this.capacity {
+			break
+		}
+		if _, exist := this.container[key]; exist {
+			continue
+		}
+		raw := raws[index]
+		if write, pending := this.unflushedNoLock(key); pending {
+			if write.Op == "delete" {
+				continue
+			}
+			raw = write.Data
+		}
+		if value, ok := this.storageValue(raw); ok {
+			this.addLastOrUpdateNoLock(key, value, true)
+			loaded++
+		}
+	}
+	return loaded, nil
+}
+
+// Contents returns a copy of every element keyed by its key, Slice keeps the
+// order.
+func (this *List[K, V]) Contents() map[K]V {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	content := make(map[K]V, len(this.container))
+	for k, v := range this.container {
+		content[k] = v.Data
+	}
+	return content
+}
+
+func (this *List[K, V]) Keys() (keys []K) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	keys = make([]K, 0, len(this.container))
+	for k := range this.container {
+		keys = append(keys, k)
+	}
+	return
+}
+
+func (this *List[K, V]) Head() (key K, element V) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if this.head != nil {
+		key = this.head.Key
+		element = this.head.Data
+	}
+	return
+}
+
+func (this *List[K, V]) Tail() (key K, element V) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if this.tail != nil {
+		key = this.tail.Key
+		element = this.tail.Data
+	}
+	return
+}
+
+func (this *List[K, V]) Next(target K) (key K, element V) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if target, ok := this.container[target]; ok && target.Next != nil {
+		key = target.Next.Key
+		element = target.Next.Data
+	}
+	return
+}
+
+func (this *List[K, V]) Prev(target K) (key K, element V) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if target, ok := this.container[target]; ok && target.Prev != nil {
+		key = target.Prev.Key
+		element = target.Prev.Data
+	}
+	return
+}
+
+func (this *List[K, V]) Remove(target K) (element V) {
+	return this.DeleteFromStorage(target, false)
+}
+
+func (this *List[K, V]) Update(key K, data V) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if val, ok := this.container[key]; ok {
+		old := val.Data
+		val.Data = data
+		this.indexNoLock(val)
+		this.broadcastUpdateNoLock(val, old)
+		this.touchNoLock(val)
+		return nil
+	} else {
+		return errors.New("data not found")
+	}
+}
+
+func (this *List[K, V]) Size() int {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return len(this.container)
+}
+
+// Sort reorders the list in place with a stable merge sort, compare reports
+// whether left has to come before right. A single SORT event carrying the new
+// head and the full key order is broadcast once the order is settled.
+func (this *List[K, V]) Sort(compare func(left V, right V) bool) error {
+	if compare == nil {
+		return errors.New("compare function is nil")
+	}
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if this.head == nil {
+		return nil
+	}
+	this.head = mergeSort(this.head, compare)
+	var prev *Component[K, V]
+	for item := this.head; item != nil; item = item.Next {
+		item.Prev = prev
+		prev = item
+	}
+	this.tail = prev
+	this.order.rebuild(this.head)
+	keys := this.keysNoLock()
+	event := this.newEventNoLock(SORT, this.head)
+	event.Order = keys
+	this.broadcastEvent(event)
+	this.reorder = true
+	this.storeOrderNoLock()
+	return nil
+}
+
+func (this *List[K, V]) keysNoLock() (keys []K) {
+	keys = make([]K, 0, len(this.container))
+	for item := this.head; item != nil; item = item.Next {
+		keys = append(keys, item.Key)
+	}
+	return
+}
+
+// mergeSort sorts the chain starting at head by its Next pointers only, Prev
+// pointers are left for the caller to rebuild.
+func mergeSort[K comparable, V any](head *Component[K, V], compare func(left V, right V) bool) *Component[K, V] {
+	if head == nil || head.Next == nil {
+		return head
+	}
+	slow, fast := head, head.Next
+	for fast != nil && fast.Next != nil {
+		slow = slow.Next
+		fast = fast.Next.Next
+	}
+	right := slow.Next
+	slow.Next = nil
+	left := mergeSort(head, compare)
+	right = mergeSort(right, compare)
+
+	merged := &Component[K, V]{}
+	tail := merged
+	for left != nil && right != nil {
+		if compare(right.Data, left.Data) {
+			tail.Next = right
+			right = right.Next
+		} else {
+			tail.Next = left
+			left = left.Next
+		}
+		tail = tail.Next
+	}
+	if left != nil {
+		tail.Next = left
+	} else {
+		tail.Next = right
+	}
+	return merged.Next
+}
@@ -0,0 +1,181 @@
+package list
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLRUKeepsNewInserts(t *testing.T) {
+	list := NewLRU[string, int](2, nil)
+	for index, key := range []string{"a", "b", "c", "d", "e"} {
+		if err := list.AddLast(key, index); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if keys := fmt.Sprint(list.Slice()); keys != "[{e 4} {d 3}]" {
+		t.Fatal(keys)
+	}
+	list.AddLastOrUpdate("d", 30)
+	list.AddLastOrUpdate("f", 5)
+	if keys := fmt.Sprint(list.Slice()); keys != "[{f 5} {d 30}]" {
+		t.Fatal(keys)
+	}
+	list.Update("d", 31)
+	list.AddLast("g", 6)
+	if keys := fmt.Sprint(list.Slice()); keys != "[{g 6} {d 31}]" {
+		t.Fatal(keys)
+	}
+}
+
+func TestStorageValueTypedBytes(t *testing.T) {
+	storage := newTestStorage()
+	typed := NewWithCheckedStorage[string, []byte](storage)
+	if err := typed.AddLast("a", []byte{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	reader := NewWithCheckedStorage[string, []byte](storage)
+	if value := reader.Find("a"); !bytes.Equal(value, []byte{1, 2, 3}) {
+		t.Fatal(value)
+	}
+
+	// an untyped list without a codec takes stored bytes as they are
+	storage.data["raw"] = []byte("raw")
+	untyped := NewWithCheckedStorage[string, interface{}](storage)
+	if value, ok := untyped.Find("raw").([]byte); !ok || string(value) != "raw" {
+		t.Fatal(untyped.Find("raw"))
+	}
+}
+
+func TestSort(t *testing.T) {
+	storage := &orderStorage{testStorage: newTestStorage()}
+	list := NewWithCheckedStorage[string, int](storage)
+	for index, value := range []int{3, 1, 2, 1, 3, 0} {
+		list.AddLast(fmt.Sprint("k", index), value)
+	}
+	subscription := list.Subscribe(4, nil)
+	defer subscription.Unsubscribe()
+	if err := list.Sort(func(left int, right int) bool { return left < right }); err != nil {
+		t.Fatal(err)
+	}
+	// equal values keep their order
+	want := "[k5 k1 k3 k2 k0 k4]"
+	if got := orderOf(list); got != want {
+		t.Fatal(got)
+	}
+	var reverse []string
+	list.IterateReverse(func(key string, value int) bool {
+		reverse = append(reverse, key)
+		return true
+	})
+	if fmt.Sprint(reverse) != "[k4 k0 k2 k3 k1 k5]" {
+		t.Fatal(reverse)
+	}
+	if head, _ := list.Head(); head != "k5" {
+		t.Fatal(head)
+	}
+	if tail, _ := list.Tail(); tail != "k4" {
+		t.Fatal(tail)
+	}
+	if prev, _ := list.Prev("k3"); prev != "k1" || list.IndexOf("k2") != 3 {
+		t.Fatal(prev, list.IndexOf("k2"))
+	}
+	if len(subscription.Events()) != 1 {
+		t.Fatal(len(subscription.Events()))
+	}
+	event := <-subscription.Events()
+	if event.Event != SORT || event.Key != "k5" || fmt.Sprint(event.Order) != want {
+		t.Fatal(event.Event, event.Key, event.Order)
+	}
+	if len(storage.orders) != 1 || fmt.Sprint(storage.orders[0]) != want {
+		t.Fatal(storage.orders)
+	}
+
+	if err := list.Sort(nil); err == nil {
+		t.Fatal("nil compare was accepted")
+	}
+	empty := New[string, int]()
+	if err := empty.Sort(func(left int, right int) bool { return left < right }); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// iterStorage is a testStorage that iterates in the order keys were first set.
+type iterStorage struct {
+	*testStorage
+	order []string
+	err   error
+}
+
+func (this *iterStorage) Add(key string, data []byte) error {
+	if _, ok := this.get(key); !ok {
+		this.order = append(this.order, key)
+	}
+	return this.testStorage.Add(key, data)
+}
+
+func (this *iterStorage) Iterate(fn func(key string, data interface{}) bool) error {
+	if this.err != nil {
+		return this.err
+	}
+	for _, key := range this.order {
+		if data, ok := this.testStorage.data[key]; ok && !fn(key, data) {
+			break
+		}
+	}
+	return nil
+}
+
+func TestLoadFromStorage(t *testing.T) {
+	storage := &iterStorage{testStorage: newTestStorage()}
+	writer := NewWithCheckedStorage[string, []byte](storage)
+	for _, key := range []string{"c", "a", "b", "d"} {
+		writer.AddLast(key, []byte(key+key))
+	}
+	writer.Remove("d")
+	storage.Add("bad", []byte("not json"))
+
+	list := NewWithCheckedStorage[string, []byte](storage)
+	list.AddLast("a", []byte("mine"))
+	subscription := list.Subscribe(8, nil)
+	defer subscription.Unsubscribe()
+	loaded, err := list.LoadFromStorage()
+	if err != nil || loaded != 2 {
+		t.Fatal(loaded, err)
+	}
+	// stored order after what the list already held, undecodable data skipped
+	if got := orderOf(list); got != "[a c b]" {
+		t.Fatal(got)
+	}
+	if string(list.Find("c")) != "cc" || string(list.Find("a")) != "mine" {
+		t.Fatal(list.Find("c"), list.Find("a"))
+	}
+	if len(subscription.Events()) != 2 {
+		t.Fatal(len(subscription.Events()))
+	}
+
+	lru := NewLRUWithCheckedStorage[string, []byte](1, storage)
+	if loaded, err := lru.LoadFromStorage(); loaded != 1 || err != nil {
+		t.Fatal(loaded, err)
+	}
+
+	storage.err = os.ErrClosed
+	if _, err := list.LoadFromStorage(); err == nil {
+		t.Fatal("iterate error was hidden")
+	}
+	if _, err := NewWithCheckedStorage[string, int](newTestStorage()).LoadFromStorage(); err == nil {
+		t.Fatal("storage without Iterate was loaded")
+	}
+}
+
+func TestKeyName(t *testing.T) {
+	key := strings.Repeat("k", 32)
+	if allocs := testing.AllocsPerRun(100, func() { keyName(key) }); allocs != 0 {
+		t.Fatal("string key allocated", allocs)
+	}
+	if keyName(42) != "42" || keyName(key) != key || keyName(struct{ A int }{1}) != "{1}" {
+		t.Fatal(keyName(42))
+	}
+}
@@ -0,0 +1,216 @@
+package list
+
+import (
+	"github.com/pkg/errors"
+)
+
+// The Move functions reposition an existing element without removing it, so
+// subscribers get a single MOVE event with the new neighbours instead of a
+// DELETE/ADD pair and the value is not written to storage again. A
+// PositionStorage is told the new place of the moved key before the list
+// changes, under the failure policy like any other write. A storage that is
+// only an OrderedStorage is given the whole order afterwards. While writes
+// are queued, by write-behind or for Repair, the order is sent once they are.
+
+func (this *List[K, V]) MoveToFront(key K) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if data, ok := this.container[key]; ok {
+		return this.placeNoLock(data, nil)
+	} else {
+		return errors.New("data not found")
+	}
+}
+
+func (this *List[K, V]) MoveToBack(key K) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if data, ok := this.container[key]; ok {
+		return this.placeNoLock(data, this.tail)
+	} else {
+		return errors.New("data not found")
+	}
+}
+
+// MoveBefore places key directly in front of target.
+func (this *List[K, V]) MoveBefore(key K, target K) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	data, other, err := this.movePairNoLock(key, target)
+	if err != nil || data == other {
+		return err
+	}
+	return this.placeNoLock(data, other.Prev)
+}
+
+// MoveAfter places key directly behind target.
+func (this *List[K, V]) MoveAfter(key K, target K) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	data, other, err := this.movePairNoLock(key, target)
+	if err != nil || data == other {
+		return err
+	}
+	return this.placeNoLock(data, other)
+}
+
+// Swap exchanges the positions of a and b. Adjacent elements need one MOVE
+// event, otherwise one is sent for each of them. When storage refuses the
+// second move the first one is taken back.
+func (this *List[K, V]) Swap(a K, b K) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	left, right, err := this.movePairNoLock(a, b)
+	if err != nil {
+		return err
+	}
+	if left == right {
+		return nil
+	}
+	if left.Next == right {
+		return this.placeNoLock(right, left.Prev)
+	} else if right.Next == left {
+		return this.placeNoLock(left, right.Prev)
+	}
+	anchor := left.Next
+	origin := left.Prev
+	if err := this.placeNoLock(left, right.Prev); err != nil {
+		return err
+	}
+	after := this.tail
+	if anchor != nil {
+		after = anchor.Prev
+	}
+	if err := this.placeNoLock(right, after); err != nil {
+		this.placeNoLock(left, origin)
+		return err
+	}
+	return nil
+}
+
+// placeNoLock moves data directly behind after, to the front when after is
+// nil, storage first.
+func (this *List[K, V]) placeNoLock(data *Component[K, V], after *Component[K, V]) error {
+	if after == data || data.Prev == after {
+		return nil
+	}
+	if err := this.storeMoveNoLock(data.Key, after); err != nil {
+		return err
+	}
+	if after == nil {
+		this.moveToFrontNoLock(data)
+	} else {
+		this.moveAfterNoLock(data, after)
+	}
+	this.storeOrderNoLock()
+	return nil
+}
+
+// storeMoveNoLock sends the move of key to a PositionStorage. Otherwise, or
+// while writes are queued, it only notes that storage is owed the order.
+func (this *List[K, V]) storeMoveNoLock(key K, after *Component[K, V]) error {
+	if this.storage == nil {
+		return nil
+	}
+	positions, ok := positionStorage(this.storage)
+	if !ok || this.reorder || this.writeBehind || this.queuedNoLock(key) || (after != nil && this.queuedNoLock(after.Key)) {
+		this.reorder = true
+		return nil
+	}
+	var previous K
+	if after != nil {
+		previous = after.Key
+	}
+	err := this.retryNoLock(func() error {
+		return positions.Move(key, previous, after == nil)
+	})
+	if err == nil {
+		return nil
+	}
+	failure := &StorageError[K]{Op: "move", Key: key, Err: err}
+	this.reportNoLock(failure)
+	if this.failure == QUEUE {
+		this.reorder = true
+		return nil
+	}
+	return failure
+}
+
+// storeOrderNoLock sends the order storage is owed unless write-behind holds
+// it back for the next Flush.
+func (this *List[K, V]) storeOrderNoLock() {
+	if !this.writeBehind {
+		this.reorderNoLock()
+	}
+}
+
+// reorderNoLock sends the whole order once no write is queued anymore, as one
+// Reorder or else as one Move per key.
+func (this *List[K, V]) reorderNoLock() {
+	if !this.reorder || this.storage == nil || this.repairs.len() > 0 || this.behind.len() > 0 || this.inflight.len() > 0 {
+		return
+	}
+	this.reorder = false
+	if ordered, ok := orderedStorage(this.storage); ok {
+		ordered.Reorder(this.keysNoLock())
+		return
+	}
+	positions, ok := positionStorage(this.storage)
+	if !ok {
+		return
+	}
+	var previous K
+	for item := this.head; item != nil; item = item.Next {
+		if err := positions.Move(item.Key, previous, item == this.head); err != nil {
+			this.reorder = true
+			this.reportNoLock(&StorageError[K]{Op: "reorder", Err: err})
+			return
+		}
+		previous = item.Key
+	}
+}
+
+func (this *List[K, V]) queuedNoLock(key K) bool {
+	_, queued := this.unflushedNoLock(key)
+	return queued
+}
+
+func (this *List[K, V]) movePairNoLock(key K, target K) (*Component[K, V], *Component[K, V], error) {
+	data, ok := this.container[key]
+	if !ok {
+		return nil, nil, errors.New("data not found")
+	}
+	other, ok := this.container[target]
+	if !ok {
+		return nil, nil, errors.New("target not found")
+	}
+	return data, other, nil
+}
+
+func (this *List[K, V]) moveToFrontNoLock(data *Component[K, V]) bool {
+	if this.head == data {
+		return false
+	}
+	this.detachForMoveNoLock(data)
+	this.linkFirstNoLock(data)
+	this.broadcastEvent(this.newEventNoLock(MOVE, data))
+	return true
+}
+
+func (this *List[K, V]) moveAfterNoLock(data *Component[K, V], target *Component[K, V]) bool {
+	if data == target || data.Prev == target {
+		return false
+	}
+	this.detachForMoveNoLock(data)
+	this.linkAfterNoLock(data, target)
+	this.broadcastEvent(this.newEventNoLock(MOVE, data))
+	return true
+}
+
+func (this *List[K, V]) detachForMoveNoLock(data *Component[K, V]) {
+	this.detachNoLock(data)
+	this.moves++
+	data.moved = this.moves
+	data.Prev = nil
+	data.Next = nil
+}
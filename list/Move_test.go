@@ -0,0 +1,134 @@
+package list
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// moveStorage records the moves and orders it is given.
+type moveStorage struct {
+	*testStorage
+	moves  []string
+	orders [][]string
+	fail   bool
+}
+
+func (this *moveStorage) Move(key string, after string, first bool) error {
+	if this.fail {
+		return os.ErrClosed
+	}
+	if first {
+		this.moves = append(this.moves, key+"@front")
+	} else {
+		this.moves = append(this.moves, key+"@"+after)
+	}
+	return nil
+}
+
+func (this *moveStorage) Reorder(keys []string) {
+	this.orders = append(this.orders, keys)
+}
+
+func newMoveList(t *testing.T) (*List[string, int], *moveStorage) {
+	storage := &moveStorage{testStorage: newTestStorage()}
+	list := NewWithCheckedStorage[string, int](storage)
+	for index, key := range []string{"a", "b", "c", "d"} {
+		if err := list.AddLast(key, index); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return list, storage
+}
+
+func TestMoveSendsOneKey(t *testing.T) {
+	list, storage := newMoveList(t)
+	list.MoveToFront("c")
+	list.MoveToBack("a")
+	list.MoveAfter("c", "d")
+	list.MoveBefore("a", "b")
+	list.Swap("b", "c")
+	if keys := orderOf(list); keys != "[a c d b]" {
+		t.Fatal(keys)
+	}
+	if moves := fmt.Sprint(storage.moves); moves != "[c@front a@d c@d a@front b@d c@a]" {
+		t.Fatal(moves)
+	}
+	if len(storage.orders) != 0 {
+		t.Fatal(storage.orders)
+	}
+}
+
+func TestMoveRollback(t *testing.T) {
+	list, storage := newMoveList(t)
+	var reported []string
+	list.SetStorageErrorHandler(func(err *StorageError[string]) {
+		reported = append(reported, err.Op)
+	})
+	storage.fail = true
+	if err := list.MoveToFront("c"); err == nil {
+		t.Fatal("failed move was not returned")
+	}
+	if err := list.Swap("a", "d"); err == nil {
+		t.Fatal("failed swap was not returned")
+	}
+	if keys := orderOf(list); keys != "[a b c d]" || len(reported) != 2 {
+		t.Fatal(keys, reported)
+	}
+
+	list.SetFailurePolicy(QUEUE, 0, 0)
+	if err := list.MoveToFront("c"); err != nil {
+		t.Fatal(err)
+	}
+	if len(storage.orders) != 1 || fmt.Sprint(storage.orders[0]) != "[c a b d]" {
+		t.Fatal(storage.orders)
+	}
+}
+
+func TestMoveWriteBehind(t *testing.T) {
+	list, storage := newMoveList(t)
+	list.SetWriteBehind(100, time.Hour)
+	list.MoveToFront("c")
+	list.MoveToFront("d")
+	if len(storage.moves) != 0 || len(storage.orders) != 0 {
+		t.Fatal(storage.moves, storage.orders)
+	}
+	if err := list.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(storage.orders) != 1 || fmt.Sprint(storage.orders[0]) != "[d c a b]" {
+		t.Fatal(storage.orders)
+	}
+	list.Close()
+}
+
+// orderStorage can only take the whole order.
+type orderStorage struct {
+	*testStorage
+	orders [][]string
+}
+
+func (this *orderStorage) Reorder(keys []string) {
+	this.orders = append(this.orders, keys)
+}
+
+func TestMoveOrderedStorage(t *testing.T) {
+	storage := &orderStorage{testStorage: newTestStorage()}
+	list := NewWithCheckedStorage[string, int](storage)
+	list.AddLast("a", 1)
+	list.AddLast("b", 2)
+	list.MoveToFront("b")
+	if len(storage.orders) != 1 || fmt.Sprint(storage.orders[0]) != "[b a]" {
+		t.Fatal(storage.orders)
+	}
+}
+
+func orderOf[V any](list *List[string, V]) string {
+	var keys []string
+	list.Iterate(func(key string, value V) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return fmt.Sprint(keys)
+}
@@ -0,0 +1,193 @@
+package list
+
+import (
+	"math/rand"
+)
+
+// Entry is one key/value pair of a positional read.
+type Entry[K comparable, V any] struct {
+	Key  K `json:"key"`
+	Data V `json:"data"`
+}
+
+// At returns the element at index, 0 is the head, in O(log n).
+func (this *List[K, V]) At(index int) (key K, element V, ok bool) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if node := this.order.at(index); node != nil {
+		return node.item.Key, node.item.Data, true
+	}
+	return
+}
+
+// IndexOf returns the position of key counted from the head in O(log n), -1
+// when it is not in the list.
+func (this *List[K, V]) IndexOf(key K) int {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if data, ok := this.container[key]; ok && data.rank != nil {
+		return data.rank.index()
+	}
+	return -1
+}
+
+// Range returns the elements from index from up to, not including, index to.
+// Both are clamped to the list, finding from costs O(log n).
+func (this *List[K, V]) Range(from int, to int) []Entry[K, V] {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if from < 0 {
+		from = 0
+	}
+	if size := orderSize(this.order.root); to > size {
+		to = size
+	}
+	if from >= to {
+		return []Entry[K, V]{}
+	}
+	entries := make([]Entry[K, V], 0, to-from)
+	item := this.order.at(from).item
+	for index := from; index < to && item != nil; index++ {
+		entries = append(entries, Entry[K, V]{Key: item.Key, Data: item.Data})
+		item = item.Next
+	}
+	return entries
+}
+
+// Slice returns every element head first.
+func (this *List[K, V]) Slice() []Entry[K, V] {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	entries := make([]Entry[K, V], 0, len(this.container))
+	for item := this.head; item != nil; item = item.Next {
+		entries = append(entries, Entry[K, V]{Key: item.Key, Data: item.Data})
+	}
+	return entries
+}
+
+// orderTree mirrors the chain in an implicit treap, a node's position is the
+// size of everything left of it, so positions cost O(log n) to find and keep
+// up to date when elements are linked or detached anywhere.
+type orderTree[K comparable, V any] struct {
+	root *orderNode[K, V]
+}
+
+type orderNode[K comparable, V any] struct {
+	item     *Component[K, V]
+	left     *orderNode[K, V]
+	right    *orderNode[K, V]
+	parent   *orderNode[K, V]
+	priority uint32
+	size     int
+}
+
+// insert puts item at index.
+func (this *orderTree[K, V]) insert(index int, item *Component[K, V]) {
+	node := &orderNode[K, V]{item: item, priority: rand.Uint32(), size: 1}
+	item.rank = node
+	left, right := orderSplit(this.root, index)
+	this.setRoot(orderMerge(orderMerge(left, node), right))
+}
+
+func (this *orderTree[K, V]) remove(item *Component[K, V]) {
+	if item.rank == nil {
+		return
+	}
+	left, right := orderSplit(this.root, item.rank.index())
+	_, right = orderSplit(right, 1)
+	this.setRoot(orderMerge(left, right))
+	item.rank = nil
+}
+
+// rebuild indexes the chain starting at head from scratch.
+func (this *orderTree[K, V]) rebuild(head *Component[K, V]) {
+	var root *orderNode[K, V]
+	for item := head; item != nil; item = item.Next {
+		node := &orderNode[K, V]{item: item, priority: rand.Uint32(), size: 1}
+		item.rank = node
+		root = orderMerge(root, node)
+	}
+	this.setRoot(root)
+}
+
+func (this *orderTree[K, V]) at(index int) *orderNode[K, V] {
+	node := this.root
+	for node != nil {
+		left := orderSize(node.left)
+		if index < left {
+			node = node.left
+		} else if index == left {
+			return node
+		} else {
+			index -= left + 1
+			node = node.right
+		}
+	}
+	return nil
+}
+
+func (this *orderTree[K, V]) setRoot(root *orderNode[K, V]) {
+	if root != nil {
+		root.parent = nil
+	}
+	this.root = root
+}
+
+func (this *orderNode[K, V]) index() int {
+	index := orderSize(this.left)
+	for node := this; node.parent != nil; node = node.parent {
+		if node == node.parent.right {
+			index += orderSize(node.parent.left) + 1
+		}
+	}
+	return index
+}
+
+func (this *orderNode[K, V]) update() {
+	this.size = 1 + orderSize(this.left) + orderSize(this.right)
+	if this.left != nil {
+		this.left.parent = this
+	}
+	if this.right != nil {
+		this.right.parent = this
+	}
+}
+
+func orderSize[K comparable, V any](node *orderNode[K, V]) int {
+	if node == nil {
+		return 0
+	}
+	return node.size
+}
+
+// orderSplit cuts the first count nodes of node off into left.
+func orderSplit[K comparable, V any](node *orderNode[K, V], count int) (left *orderNode[K, V], right *orderNode[K, V]) {
+	if node == nil {
+		return nil, nil
+	}
+	if orderSize(node.left) < count {
+		node.right, right = orderSplit(node.right, count-orderSize(node.left)-1)
+		node.update()
+		return node, right
+	}
+	left, node.left = orderSplit(node.left, count)
+	node.update()
+	return left, node
+}
+
+func orderMerge[K comparable, V any](left *orderNode[K, V], right *orderNode[K, V]) *orderNode[K, V] {
+	if left == nil {
+		return right
+	}
+	if right == nil {
+		return left
+	}
+	if left.priority > right.priority {
+		left.right = orderMerge(left.right, right)
+		left.update()
+		return left
+	}
+	right.left = orderMerge(left, right.left)
+	right.update()
+	return right
+}
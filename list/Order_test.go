@@ -0,0 +1,62 @@
+package list
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestOrderBounds(t *testing.T) {
+	empty := New[string, int]()
+	if _, _, ok := empty.At(0); ok {
+		t.Fatal("At on an empty list")
+	}
+	if got := empty.Range(-1, 5); got == nil || len(got) != 0 {
+		t.Fatal(got)
+	}
+	if got := empty.Slice(); got == nil || len(got) != 0 {
+		t.Fatal(got)
+	}
+
+	list := newABCD()
+	for index, key := range []string{"a", "b", "c", "d"} {
+		if got, value, ok := list.At(index); !ok || got != key || value != index {
+			t.Fatal(index, got, value, ok)
+		}
+	}
+	for _, index := range []int{-1, -100, 4, 100} {
+		if key, value, ok := list.At(index); ok || key != "" || value != 0 {
+			t.Fatal(index, key, value)
+		}
+	}
+	cases := []struct {
+		from, to int
+		want     string
+	}{
+		{0, 4, "[{a 0} {b 1} {c 2} {d 3}]"},
+		{1, 3, "[{b 1} {c 2}]"},
+		{-5, 2, "[{a 0} {b 1}]"},
+		{2, 100, "[{c 2} {d 3}]"},
+		{-5, 100, "[{a 0} {b 1} {c 2} {d 3}]"},
+		{3, 3, "[]"},
+		{3, 1, "[]"},
+		{4, 10, "[]"},
+		{-3, -1, "[]"},
+	}
+	for _, test := range cases {
+		if got := fmt.Sprint(list.Range(test.from, test.to)); got != test.want {
+			t.Fatal(test.from, test.to, got)
+		}
+	}
+
+	list.Remove("b")
+	list.MoveToFront("d")
+	if got := fmt.Sprint(list.Slice()); got != "[{d 3} {a 0} {c 2}]" {
+		t.Fatal(got)
+	}
+	if key, _, _ := list.At(2); key != "c" || list.IndexOf("b") != -1 || list.IndexOf("c") != 2 {
+		t.Fatal(key, list.IndexOf("c"))
+	}
+	if got := fmt.Sprint(list.Range(1, 5)); got != "[{a 0} {c 2}]" {
+		t.Fatal(got)
+	}
+}
@@ -0,0 +1,75 @@
+package list
+
+// Filter returns the elements pred accepts, head first. pred runs with the
+// list locked and must not call back into it, the same goes for every
+// predicate below.
+func (this *List[K, V]) Filter(pred func(key K, value V) bool) []Entry[K, V] {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	entries := make([]Entry[K, V], 0)
+	for item := this.head; item != nil; item = item.Next {
+		if pred(item.Key, item.Data) {
+			entries = append(entries, Entry[K, V]{Key: item.Key, Data: item.Data})
+		}
+	}
+	return entries
+}
+
+// FindFirst returns the element nearest to the head that pred accepts.
+func (this *List[K, V]) FindFirst(pred func(key K, value V) bool) (key K, element V, ok bool) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	for item := this.head; item != nil; item = item.Next {
+		if pred(item.Key, item.Data) {
+			return item.Key, item.Data, true
+		}
+	}
+	return
+}
+
+func (this *List[K, V]) CountIf(pred func(key K, value V) bool) (count int) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	for item := this.head; item != nil; item = item.Next {
+		if pred(item.Key, item.Data) {
+			count++
+		}
+	}
+	return
+}
+
+// RemoveIf removes every element pred accepts all or nothing: the storage
+// deletes are sent together like a Batch, then a DELETE is broadcast for each
+// element. When storage refuses the deletes every element stays in the list
+// and the error is returned.
+func (this *List[K, V]) RemoveIf(pred func(key K, value V) bool) (int, error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	tx, err := this.commitNoLock(func(tx *ListTx[K, V]) error {
+		for item := this.head; item != nil; {
+			next := item.Next
+			if pred(item.Key, item.Data) {
+				tx.Remove(item.Key)
+			}
+			item = next
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, event := range tx.events {
+		this.sequence++
+		event.Sequence = this.sequence
+		this.broadcastEvent(event)
+	}
+	return len(tx.events), nil
+}
+
+// Clear removes every element the way RemoveIf does.
+func (this *List[K, V]) Clear() error {
+	_, err := this.RemoveIf(func(K, V) bool {
+		return true
+	})
+	return err
+}
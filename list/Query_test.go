@@ -0,0 +1,61 @@
+package list
+
+import "testing"
+
+func TestRemoveIfAllOrNothing(t *testing.T) {
+	storage := newTestStorage()
+	list := NewWithCheckedStorage[string, int](storage)
+	for index, key := range []string{"a", "b", "c", "d", "e"} {
+		list.AddLast(key, index)
+	}
+	even := func(key string, value int) bool {
+		return value%2 == 0
+	}
+	subscription := list.Subscribe(4, nil)
+	defer subscription.Unsubscribe()
+
+	// the second delete fails, the first one is put back
+	storage.failAt = storage.calls + 2
+	if removed, err := list.RemoveIf(even); removed != 0 || err == nil {
+		t.Fatal(removed, err)
+	}
+	if got := orderOf(list); got != "[a b c d e]" {
+		t.Fatal(got)
+	}
+	for _, key := range []string{"a", "c", "e"} {
+		if _, ok := storage.get(key); !ok {
+			t.Fatal(key, "was left deleted in storage")
+		}
+	}
+	if len(subscription.Events()) != 0 {
+		t.Fatal("a failed RemoveIf was broadcast")
+	}
+
+	if removed, err := list.RemoveIf(even); removed != 3 || err != nil {
+		t.Fatal(removed, err)
+	}
+	if got := orderOf(list); got != "[b d]" {
+		t.Fatal(got)
+	}
+	var sequence uint64
+	for _, key := range []string{"a", "c", "e"} {
+		event := <-subscription.Events()
+		if event.Event != DELETE || event.Key != key || event.Sequence <= sequence {
+			t.Fatal(key, event)
+		}
+		sequence = event.Sequence
+	}
+	subscription.Unsubscribe()
+	if _, ok := storage.get("a"); ok {
+		t.Fatal("a is still stored")
+	}
+
+	storage.setDown(true)
+	if err := list.Clear(); err == nil || list.Size() != 2 {
+		t.Fatal(err, list.Size())
+	}
+	storage.setDown(false)
+	if err := list.Clear(); err != nil || list.Size() != 0 || len(storage.data) != 0 {
+		t.Fatal(err, list.Size(), len(storage.data))
+	}
+}
@@ -0,0 +1,163 @@
+package list
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"math"
+)
+
+// waitQueue lines up blocked callers first come first served. waking counts
+// the callers that were handed a turn but have not taken the lock yet, each
+// of them has one element, or one free place, set aside.
+type waitQueue struct {
+	waiters []chan struct{}
+	waking  int
+}
+
+// SetBound limits how many elements PushWait lets into the list, 0 or less
+// means no limit. Other adds ignore the bound.
+func (this *List[K, V]) SetBound(bound int) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	this.bound = bound
+	this.signalNoLock()
+}
+
+// PushWait appends key like AddLast, but while the list holds its bound it
+// blocks until a place is free or ctx ends. Blocked producers get their turn
+// in the order they came.
+func (this *List[K, V]) PushWait(ctx context.Context, key K, data V) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if _, ok := this.container[key]; ok {
+		return errors.New("duplicate key")
+	}
+	if err := this.awaitNoLock(ctx, &this.producers, this.roomNoLock); err != nil {
+		return err
+	}
+	if _, ok := this.container[key]; ok {
+		this.signalNoLock()
+		return errors.New("duplicate key")
+	}
+	if _, err := this.addLastNoLock(key, data); err != nil {
+		// the place stays free, hand it to the next producer
+		this.signalNoLock()
+		return err
+	}
+	return nil
+}
+
+// PopFirstWait removes the head like RemoveFirst, but an empty list blocks it
+// until an element arrives or ctx ends, then ctx.Err() is returned. Blocked
+// consumers get their turn in the order they came.
+func (this *List[K, V]) PopFirstWait(ctx context.Context) (key K, element V, err error) {
+	return this.popWait(ctx, false)
+}
+
+// PopLastWait is PopFirstWait for the tail.
+func (this *List[K, V]) PopLastWait(ctx context.Context) (key K, element V, err error) {
+	return this.popWait(ctx, true)
+}
+
+func (this *List[K, V]) popWait(ctx context.Context, last bool) (key K, element V, err error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	err = this.awaitNoLock(ctx, &this.consumers, func() int {
+		return len(this.container)
+	})
+	if err != nil {
+		return
+	}
+	data := this.head
+	if last {
+		data = this.tail
+	}
+	if err = this.storageDelete(data.Key); err != nil {
+		this.signalNoLock()
+		return
+	}
+	this.unlinkNoLock(data)
+	this.broadcastEvent(this.newEventNoLock(DELETE, data))
+	return data.Key, data.Data, nil
+}
+
+// awaitNoLock returns with the list locked once the caller's turn has come and
+// available is above zero, or with ctx.Err() when ctx ends first. A caller
+// only skips the line when nobody waits and nothing is set aside.
+func (this *List[K, V]) awaitNoLock(ctx context.Context, queue *waitQueue, available func() int) error {
+	if len(queue.waiters) == 0 && available() > queue.waking {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	signal := queue.push(false)
+	for {
+		this.locker.Unlock()
+		select {
+		case <-signal:
+			this.locker.Lock()
+			queue.waking--
+			if available() > queue.waking {
+				return nil
+			}
+			// what was set aside is gone, wait again at the front
+			signal = queue.push(true)
+		case <-ctx.Done():
+			this.locker.Lock()
+			if !queue.cancel(signal) {
+				// the turn came meanwhile, pass it on
+				queue.waking--
+				this.signalNoLock()
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+// signalNoLock hands turns to waiting consumers and producers for what the
+// list can give them now, it runs whenever elements are linked or unlinked.
+func (this *List[K, V]) signalNoLock() {
+	this.consumers.wake(len(this.container))
+	this.producers.wake(this.roomNoLock())
+}
+
+// roomNoLock is how many more elements PushWait may add.
+func (this *List[K, V]) roomNoLock() int {
+	if this.bound <= 0 {
+		return math.MaxInt
+	}
+	return this.bound - len(this.container)
+}
+
+func (this *waitQueue) push(front bool) chan struct{} {
+	signal := make(chan struct{}, 1)
+	if front {
+		this.waiters = append([]chan struct{}{signal}, this.waiters...)
+	} else {
+		this.waiters = append(this.waiters, signal)
+	}
+	return signal
+}
+
+// wake signals waiters from the front until available is set aside.
+func (this *waitQueue) wake(available int) {
+	for len(this.waiters) > 0 && available > this.waking {
+		signal := this.waiters[0]
+		this.waiters[0] = nil
+		this.waiters = this.waiters[1:]
+		this.waking++
+		signal <- struct{}{}
+	}
+}
+
+// cancel takes signal out of the line, false when it was signalled already.
+func (this *waitQueue) cancel(signal chan struct{}) bool {
+	for index, waiter := range this.waiters {
+		if waiter == signal {
+			this.waiters = append(this.waiters[:index], this.waiters[index+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package list
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// waiting polls until queue holds count waiters.
+func waiting(t *testing.T, list *List[string, int], queue *waitQueue, count int) {
+	t.Helper()
+	eventually(t, func() bool {
+		list.locker.Lock()
+		defer list.locker.Unlock()
+		return len(queue.waiters) == count
+	})
+}
+
+type popResult struct {
+	consumer int
+	key      string
+	err      error
+}
+
+func popper(list *List[string, int], ctx context.Context, consumer int, results chan<- popResult) {
+	key, _, err := list.PopFirstWait(ctx)
+	results <- popResult{consumer: consumer, key: key, err: err}
+}
+
+func nextResult(t *testing.T, results <-chan popResult) popResult {
+	t.Helper()
+	select {
+	case result := <-results:
+		return result
+	case <-time.After(time.Second):
+		t.Fatal("no consumer returned")
+	}
+	return popResult{}
+}
+
+func TestPopWaitFIFO(t *testing.T) {
+	list := New[string, int]()
+	results := make(chan popResult, 3)
+	for consumer := 0; consumer < 3; consumer++ {
+		go popper(list, context.Background(), consumer, results)
+		waiting(t, list, &list.consumers, consumer+1)
+	}
+	for consumer := 0; consumer < 3; consumer++ {
+		list.AddLast(fmt.Sprint(consumer), consumer)
+		if result := nextResult(t, results); result.consumer != consumer || result.key != fmt.Sprint(consumer) || result.err != nil {
+			t.Fatal(consumer, result)
+		}
+	}
+
+	list.AddLast("a", 0)
+	list.AddLast("b", 1)
+	if key, _, err := list.PopLastWait(context.Background()); key != "b" || err != nil {
+		t.Fatal(key, err)
+	}
+}
+
+func TestPopWaitCancel(t *testing.T) {
+	list := New[string, int]()
+	results := make(chan popResult, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	go popper(list, ctx, 0, results)
+	waiting(t, list, &list.consumers, 1)
+	go popper(list, context.Background(), 1, results)
+	waiting(t, list, &list.consumers, 2)
+
+	cancel()
+	if result := nextResult(t, results); result.consumer != 0 || result.err != context.Canceled {
+		t.Fatal(result)
+	}
+	list.AddLast("a", 0)
+	if result := nextResult(t, results); result.consumer != 1 || result.key != "a" {
+		t.Fatal(result)
+	}
+
+	// a consumer cancelled after its turn came passes the turn on
+	for round := 0; round < 20; round++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		go popper(list, ctx, 0, results)
+		waiting(t, list, &list.consumers, 1)
+		go popper(list, context.Background(), 1, results)
+		waiting(t, list, &list.consumers, 2)
+		list.locker.Lock()
+		cancel()
+		list.addLastNoLock("x", round)
+		list.locker.Unlock()
+
+		got := map[int]popResult{}
+		first := nextResult(t, results)
+		got[first.consumer] = first
+		if first.consumer == 0 && first.err == nil {
+			// the turn won over the cancel, the other consumer still waits
+			list.AddLast("y", round)
+		}
+		second := nextResult(t, results)
+		got[second.consumer] = second
+		if got[0].err == nil {
+			if got[0].key != "x" || got[1].key != "y" {
+				t.Fatal(round, got)
+			}
+		} else if got[0].err != context.Canceled || got[1].key != "x" {
+			t.Fatal(round, got)
+		}
+		if list.Size() != 0 || list.consumers.waking != 0 {
+			t.Fatal(round, list.Size(), list.consumers.waking)
+		}
+	}
+}
+
+func TestPushWaitBound(t *testing.T) {
+	list := New[string, int]()
+	list.SetBound(2)
+	ctx := context.Background()
+	if list.PushWait(ctx, "a", 0) != nil || list.PushWait(ctx, "b", 1) != nil {
+		t.Fatal("push below the bound blocked or failed")
+	}
+	if err := list.PushWait(ctx, "a", 2); err == nil {
+		t.Fatal("duplicate key was pushed")
+	}
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := list.PushWait(timeout, "c", 2); err != context.DeadlineExceeded {
+		t.Fatal(err)
+	}
+
+	pushed := make(chan error, 1)
+	go func() {
+		pushed <- list.PushWait(ctx, "c", 2)
+	}()
+	waiting(t, list, &list.producers, 1)
+	// other adds ignore the bound
+	list.AddLast("d", 3)
+	list.RemoveFirst()
+	select {
+	case err := <-pushed:
+		t.Fatal("push went past the bound", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	list.RemoveFirst()
+	if err := <-pushed; err != nil || list.Size() != 2 {
+		t.Fatal(err, list.Size())
+	}
+	if got := orderOf(list); got != "[d c]" {
+		t.Fatal(got)
+	}
+	list.SetBound(0)
+	if err := list.PushWait(ctx, "e", 4); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPopWaitBatchRollback(t *testing.T) {
+	list := New[string, int]()
+	results := make(chan popResult, 1)
+	go popper(list, context.Background(), 0, results)
+	waiting(t, list, &list.consumers, 1)
+	err := list.Batch(func(tx *ListTx[string, int]) error {
+		tx.AddLast("a", 0)
+		return fmt.Errorf("roll back")
+	})
+	if err == nil {
+		t.Fatal("batch committed")
+	}
+	// the woken consumer finds nothing and waits again
+	waiting(t, list, &list.consumers, 1)
+	select {
+	case result := <-results:
+		t.Fatal("consumer returned from a rolled back batch", result)
+	default:
+	}
+	list.AddLast("b", 1)
+	if result := nextResult(t, results); result.key != "b" || result.err != nil {
+		t.Fatal(result)
+	}
+}
@@ -0,0 +1,110 @@
+package list
+
+import (
+	"time"
+)
+
+// storageLoad is one Storage.Get that concurrent misses of the same key wait
+// for instead of asking storage again.
+type storageLoad struct {
+	done  chan struct{}
+	raw   interface{}
+	err   error
+	stale bool // the key was written while the load ran
+}
+
+// SetMissTTL makes Find remember for d that storage does not have a key, so
+// repeated misses do not reach storage. d <= 0 turns it off and forgets what
+// was remembered. Writing a key through the list forgets its miss.
+func (this *List[K, V]) SetMissTTL(d time.Duration) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	this.missTTL = d
+	if d <= 0 {
+		this.misses = nil
+	}
+}
+
+// loadNoLock reads key from storage for Find. A write still queued for key
+// answers instead of storage, a remembered miss answers nil. Otherwise the
+// first caller unlocks the list around Storage.Get and later callers for the
+// same key wait for its result, owner tells them apart. ok is false when the
+// key was written meanwhile and the result must not be used. A failed read
+// counts as a miss.
+func (this *List[K, V]) loadNoLock(key K) (raw interface{}, owner bool, ok bool) {
+	if this.storage == nil {
+		return nil, true, true
+	}
+	if write, pending := this.unflushedNoLock(key); pending {
+		if write.Op == "delete" {
+			return nil, true, true
+		}
+		return write.Data, true, true
+	}
+	if at, found := this.misses[key]; found {
+		if time.Now().Before(at) {
+			return nil, true, true
+		}
+		delete(this.misses, key)
+	}
+
+	load, found := this.loads[key]
+	if found {
+		this.locker.Unlock()
+		<-load.done
+		this.locker.Lock()
+	} else {
+		owner = true
+		load = &storageLoad{done: make(chan struct{})}
+		if this.loads == nil {
+			this.loads = make(map[K]*storageLoad)
+		}
+		this.loads[key] = load
+		storage := this.storage
+		this.locker.Unlock()
+		load.raw, load.err = storage.Get(key)
+		this.locker.Lock()
+		delete(this.loads, key)
+		close(load.done)
+		if load.err != nil {
+			this.reportNoLock(&StorageError[K]{Op: "get", Key: key, Err: load.err})
+		} else if load.raw == nil && !load.stale {
+			this.missNoLock(key)
+		}
+	}
+	if load.stale {
+		return nil, owner, false
+	}
+	return load.raw, owner, true
+}
+
+func (this *List[K, V]) missNoLock(key K) {
+	if this.missTTL <= 0 {
+		return
+	}
+	if this.misses == nil {
+		this.misses = make(map[K]time.Time)
+	}
+	now := time.Now()
+	// expired misses are only dropped when the map has doubled
+	if len(this.misses) >= this.missSweep {
+		for key, at := range this.misses {
+			if !now.Before(at) {
+				delete(this.misses, key)
+			}
+		}
+		this.missSweep = 2 * len(this.misses)
+		if this.missSweep < 64 {
+			this.missSweep = 64
+		}
+	}
+	this.misses[key] = now.Add(this.missTTL)
+}
+
+// forgetNoLock drops what read-through knows about key before it is written.
+func (this *List[K, V]) forgetNoLock(key K) {
+	delete(this.misses, key)
+	if load, ok := this.loads[key]; ok {
+		load.stale = true
+	}
+}
@@ -0,0 +1,155 @@
+package list
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// gateStorage counts Gets and, while gated, holds each Get after it read the
+// data until release is called.
+type gateStorage struct {
+	*testStorage
+	gets    int
+	gate    chan struct{}
+	entered chan struct{}
+}
+
+func newGateStorage() *gateStorage {
+	return &gateStorage{testStorage: newTestStorage(), entered: make(chan struct{}, 16)}
+}
+
+func (this *gateStorage) Get(key string) (interface{}, error) {
+	raw, err := this.testStorage.Get(key)
+	this.locker.Lock()
+	this.gets++
+	gate := this.gate
+	this.locker.Unlock()
+	this.entered <- struct{}{}
+	if gate != nil {
+		<-gate
+	}
+	return raw, err
+}
+
+func (this *gateStorage) hold() {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	this.gate = make(chan struct{})
+}
+
+func (this *gateStorage) release() {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	close(this.gate)
+	this.gate = nil
+}
+
+func (this *gateStorage) count() int {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return this.gets
+}
+
+func (this *gateStorage) wait(t *testing.T) {
+	t.Helper()
+	select {
+	case <-this.entered:
+	case <-time.After(time.Second):
+		t.Fatal("Get was not called")
+	}
+}
+
+func TestReadThroughSingleFlight(t *testing.T) {
+	storage := newGateStorage()
+	storage.data["a"] = []byte("1")
+	list := NewWithCheckedStorage[string, int](storage)
+	storage.hold()
+	var group sync.WaitGroup
+	found := make(chan int, 5)
+	for i := 0; i < 5; i++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			found <- list.Find("a")
+		}()
+	}
+	storage.wait(t)
+	eventually(t, func() bool {
+		// the four others wait for the running load
+		list.locker.Lock()
+		defer list.locker.Unlock()
+		return list.loads["a"] != nil
+	})
+	time.Sleep(10 * time.Millisecond)
+	storage.release()
+	group.Wait()
+	close(found)
+	for value := range found {
+		if value != 1 {
+			t.Fatal(value)
+		}
+	}
+	if storage.count() != 1 || list.Size() != 1 {
+		t.Fatal(storage.count(), list.Size())
+	}
+}
+
+func TestReadThroughStaleLoad(t *testing.T) {
+	storage := newGateStorage()
+	storage.data["a"] = []byte("1")
+	list := NewWithCheckedStorage[string, int](storage)
+	storage.hold()
+	found := make(chan int, 1)
+	go func() {
+		found <- list.Find("a")
+	}()
+	storage.wait(t)
+	// a is written and deleted while the load of the old value runs
+	list.AddLast("a", 2)
+	list.Remove("a")
+	storage.release()
+	if value := <-found; value != 0 {
+		t.Fatal("stale load returned", value)
+	}
+	if storage.count() != 2 || list.Size() != 0 {
+		t.Fatal(storage.count(), list.Size())
+	}
+}
+
+func TestReadThroughMissTTL(t *testing.T) {
+	storage := newGateStorage()
+	list := NewWithCheckedStorage[string, int](storage)
+	list.SetMissTTL(20 * time.Millisecond)
+	list.Find("x")
+	list.Find("x")
+	if storage.count() != 1 {
+		t.Fatal(storage.count())
+	}
+	time.Sleep(30 * time.Millisecond)
+	list.Find("x")
+	if storage.count() != 2 {
+		t.Fatal(storage.count())
+	}
+
+	// a miss hides what storage got behind the list's back
+	list.SetMissTTL(time.Hour)
+	list.Find("y")
+	storage.set("y", []byte("1"))
+	if list.Find("y") != 0 || storage.count() != 3 {
+		t.Fatal(storage.count())
+	}
+	// writing through the list forgets it
+	list.AddLast("y", 5)
+	list.DeleteFromStorage("y", true)
+	if list.Find("y") != 5 || storage.count() != 4 {
+		t.Fatal(storage.count())
+	}
+
+	list.SetMissTTL(0)
+	list.Find("z")
+	list.Find("z")
+	if storage.count() != 6 {
+		t.Fatal(storage.count())
+	}
+}
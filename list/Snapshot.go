@@ -0,0 +1,173 @@
+package list
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"io"
+	"time"
+)
+
+// SNAPSHOT is the encoding written by Snapshot. Restore recognises both.
+type SNAPSHOT int
+
+const (
+	SNAPSHOT_JSON SNAPSHOT = iota
+	SNAPSHOT_GOB
+)
+
+const snapshotVersion = 1
+
+// snapshotImage is what Snapshot writes: the elements head first plus the
+// event sequence. Gob needs concrete types stored behind interface values to
+// be registered with gob.Register.
+type snapshotImage[K comparable, V any] struct {
+	Version  int
+	Created  time.Time
+	Sequence uint64
+	Entries  []snapshotEntry[K, V]
+}
+
+type snapshotEntry[K comparable, V any] struct {
+	Key     K
+	Data    V
+	Expires *time.Time `json:",omitempty"`
+}
+
+func (this *List[K, V]) SetSnapshotFormat(format SNAPSHOT) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	this.snapshot = format
+}
+
+// Snapshot writes every element in list order to w. The list is locked only
+// while the elements are copied, not while they are encoded.
+func (this *List[K, V]) Snapshot(w io.Writer) error {
+	this.locker.Lock()
+	image := snapshotImage[K, V]{
+		Version:  snapshotVersion,
+		Created:  time.Now(),
+		Sequence: this.sequence,
+		Entries:  make([]snapshotEntry[K, V], 0, len(this.container)),
+	}
+	for item := this.head; item != nil; item = item.Next {
+		entry := snapshotEntry[K, V]{Key: item.Key, Data: item.Data}
+		if expiry, ok := this.expires[item.Key]; ok {
+			at := expiry.at
+			entry.Expires = &at
+		}
+		image.Entries = append(image.Entries, entry)
+	}
+	format := this.snapshot
+	this.locker.Unlock()
+
+	switch format {
+	case SNAPSHOT_GOB:
+		return gob.NewEncoder(w).Encode(&image)
+	default:
+		return json.NewEncoder(w).Encode(&image)
+	}
+}
+
+// Restore replaces the whole content of the list with a snapshot read from r.
+// The snapshot is decoded before the list is touched, so a broken input leaves
+// the list as it was. Elements whose TTL ran out in the meantime are skipped.
+// Storage is not written, subscribers get one RESTORE event with the new order.
+// Event sequence numbers carry on from the snapshot's when it is ahead of the
+// list. The list keeps its own capacity, an LRU list evicts down to it without
+// purging the evicted keys from storage.
+func (this *List[K, V]) Restore(r io.Reader) error {
+	reader := bufio.NewReader(r)
+	var image snapshotImage[K, V]
+	if isJSON(reader) {
+		if err := json.NewDecoder(reader).Decode(&image); err != nil {
+			return errors.Wrap(err, "decode json snapshot")
+		}
+	} else {
+		if err := gob.NewDecoder(reader).Decode(&image); err != nil {
+			return errors.Wrap(err, "decode gob snapshot")
+		}
+	}
+	if image.Version != snapshotVersion {
+		return errors.Errorf("unsupported snapshot version %d", image.Version)
+	}
+
+	now := time.Now()
+	container := make(map[K]*Component[K, V], len(image.Entries))
+	expires := make(map[K]time.Time)
+	var head, tail *Component[K, V]
+	for _, entry := range image.Entries {
+		if _, ok := container[entry.Key]; ok {
+			return errors.New("duplicate key in snapshot")
+		}
+		if entry.Expires != nil {
+			if !entry.Expires.After(now) {
+				continue
+			}
+			expires[entry.Key] = *entry.Expires
+		}
+		temp := &Component[K, V]{Key: entry.Key, Data: entry.Data, Prev: tail}
+		if tail == nil {
+			head = temp
+		} else {
+			tail.Next = temp
+		}
+		tail = temp
+		container[entry.Key] = temp
+	}
+
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	this.container = container
+	this.head = head
+	this.tail = tail
+	this.order.rebuild(head)
+	this.reindexNoLock()
+	this.signalNoLock()
+	this.expires = nil
+	this.deadlines = nil
+	for key, at := range expires {
+		this.setDeadlineNoLock(key, at)
+	}
+	if len(expires) > 0 {
+		this.startReaperNoLock(context.Background(), DefaultReapInterval)
+	}
+	this.sequence = max(this.sequence, image.Sequence)
+	first := head
+	if first == nil {
+		first = &Component[K, V]{}
+	}
+	event := this.newEventNoLock(RESTORE, first)
+	event.Order = this.keysNoLock()
+	this.broadcastEvent(event)
+	this.trimNoLock(nil, false)
+	return nil
+}
+
+// isJSON peeks at the first non blank bytes, a JSON snapshot is an object. A
+// gob stream may start with '{' too, as the length of its first message, but
+// what follows is never a key or the end of the object.
+func isJSON(reader *bufio.Reader) bool {
+	opened := false
+	for i := 1; ; i++ {
+		peek, err := reader.Peek(i)
+		if err != nil || len(peek) < i {
+			return false
+		}
+		switch peek[i-1] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '{':
+			if opened {
+				return false
+			}
+			opened = true
+		case '"', '}':
+			return opened
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package list
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRestoreCarriesSequence(t *testing.T) {
+	for _, format := range []SNAPSHOT{SNAPSHOT_JSON, SNAPSHOT_GOB} {
+		source := New[string, int]()
+		for index, key := range []string{"a", "b", "c"} {
+			source.AddLast(key, index)
+		}
+		source.Update("a", 10)
+		source.SetSnapshotFormat(format)
+		var buffer bytes.Buffer
+		if err := source.Snapshot(&buffer); err != nil {
+			t.Fatal(err)
+		}
+
+		target := NewLRU[string, int](2, nil)
+		subscription := target.Subscribe(4, nil)
+		if err := target.Restore(&buffer); err != nil {
+			t.Fatal(format, err)
+		}
+		event := <-subscription.Events()
+		subscription.Unsubscribe()
+		if event.Event != RESTORE || event.Sequence != source.sequence+1 {
+			t.Fatal(format, event.Event, event.Sequence, source.sequence)
+		}
+		// the LRU keeps its own capacity and drops the tail
+		if got := orderOf(target); got != "[a b]" || target.Find("a") != 10 {
+			t.Fatal(format, got)
+		}
+	}
+}
+
+func TestIsJSON(t *testing.T) {
+	cases := map[string]bool{
+		`{"Version":1}`:    true,
+		" \n{ \"Version\"": true,
+		"{}":               true,
+		"{\xff\x81\x03":    false, // a gob stream whose first message is 123 bytes
+		"\x7f\xff":         false,
+		"":                 false,
+	}
+	for input, want := range cases {
+		if got := isJSON(bufio.NewReader(strings.NewReader(input))); got != want {
+			t.Fatalf("%q %v", input, got)
+		}
+	}
+}
+
+func TestRestoreDoesNotPurge(t *testing.T) {
+	source := New[string, int]()
+	for index, key := range []string{"a", "b", "c"} {
+		source.AddLast(key, index)
+	}
+	var buffer bytes.Buffer
+	if err := source.Snapshot(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	storage := newTestStorage()
+	storage.data["c"] = []byte("stored")
+	target := NewLRUWithCheckedStorage[string, int](2, storage)
+	target.SetPurgeOnEvict(true)
+	if err := target.Restore(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	if got := orderOf(target); got != "[a b]" {
+		t.Fatal(got)
+	}
+	if data, ok := storage.get("c"); !ok || data != "stored" || storage.calls != 0 {
+		t.Fatal("storage was written", storage.calls)
+	}
+}
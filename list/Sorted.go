@@ -0,0 +1,321 @@
+package list
+
+import (
+	"github.com/pkg/errors"
+	"math/rand"
+)
+
+const sortedMaxLevel = 32
+
+// SortedList keeps its elements ordered by less. An indexable skip list finds
+// the place of an insert and the element at a rank in O(log n), the elements
+// themselves are the Components of an inner List, so events, subscriptions,
+// the codec and storage work as they do for List. Elements that compare equal
+// stay in insertion order.
+type SortedList[K comparable, V any] struct {
+	list     *List[K, V]
+	less     func(left V, right V) bool
+	nodes    map[K]*sortedNode[K, V]
+	header   *sortedNode[K, V]
+	level    int
+	length   int
+	sequence uint64
+}
+
+// sortedNode is one skip list entry, span[i] counts the elements next[i]
+// jumps over including itself.
+type sortedNode[K comparable, V any] struct {
+	item     *Component[K, V]
+	sequence uint64
+	next     []*sortedNode[K, V]
+	span     []int
+}
+
+func NewSorted[K comparable, V any](less func(left V, right V) bool) *SortedList[K, V] {
+	return newSorted(New[K, V](), less)
+}
+
+func NewSortedWithStorage[K comparable, V any](less func(left V, right V) bool, storage Storage[K]) *SortedList[K, V] {
+	return newSorted(NewWithStorage[K, V](storage), less)
+}
+
+func newSorted[K comparable, V any](list *List[K, V], less func(left V, right V) bool) *SortedList[K, V] {
+	return &SortedList[K, V]{
+		list:   list,
+		less:   less,
+		nodes:  make(map[K]*sortedNode[K, V]),
+		header: &sortedNode[K, V]{next: make([]*sortedNode[K, V], sortedMaxLevel), span: make([]int, sortedMaxLevel)},
+		level:  1,
+	}
+}
+
+// Add inserts key at its sorted place, after the elements equal to data.
+func (this *SortedList[K, V]) Add(key K, data V) error {
+	this.list.locker.Lock()
+	defer this.list.locker.Unlock()
+	if _, ok := this.list.container[key]; ok {
+		return errors.New("duplicate key")
+	}
+	payload, err := this.list.encodeNoLock(data)
+	if err != nil {
+		return err
+	}
+	if err := this.list.storageAdd(key, payload); err != nil {
+		return err
+	}
+	temp := &Component[K, V]{Data: data, Key: key}
+	this.insertNoLock(temp)
+	this.list.broadcastEvent(this.list.newEventNoLock(ADD, temp))
+	return nil
+}
+
+// Update replaces the value of key, writes it to storage and moves the
+// element to its new sorted place.
+func (this *SortedList[K, V]) Update(key K, data V) error {
+	this.list.locker.Lock()
+	defer this.list.locker.Unlock()
+	node, ok := this.nodes[key]
+	if !ok {
+		return errors.New("data not found")
+	}
+	payload, err := this.list.encodeNoLock(data)
+	if err != nil {
+		return err
+	}
+	if err := this.list.storageUpdate(key, payload); err != nil {
+		return err
+	}
+	temp := node.item
+	this.deleteNoLock(node)
+	this.list.unlinkNoLock(temp)
+	old := temp.Data
+	temp.Data = data
+	temp.Next, temp.Prev = nil, nil
+	this.insertNoLock(temp)
+	this.list.broadcastUpdateNoLock(temp, old)
+	return nil
+}
+
+func (this *SortedList[K, V]) Remove(target K) (element V) {
+	this.list.locker.Lock()
+	defer this.list.locker.Unlock()
+	node, ok := this.nodes[target]
+	if !ok {
+		return
+	}
+	if this.list.storageDelete(target) != nil {
+		return
+	}
+	this.deleteNoLock(node)
+	this.list.unlinkNoLock(node.item)
+	this.list.broadcastEvent(this.list.newEventNoLock(DELETE, node.item))
+	return node.item.Data
+}
+
+// Find only looks at the list, it does not read through to storage.
+func (this *SortedList[K, V]) Find(target K) (element V) {
+	this.list.locker.Lock()
+	defer this.list.locker.Unlock()
+	if data, ok := this.list.container[target]; ok {
+		element = data.Data
+	}
+	return
+}
+
+// At returns the element at index, 0 is the smallest.
+func (this *SortedList[K, V]) At(index int) (key K, element V, ok bool) {
+	this.list.locker.Lock()
+	defer this.list.locker.Unlock()
+	if node := this.atNoLock(index + 1); node != nil {
+		return node.item.Key, node.item.Data, true
+	}
+	return
+}
+
+// IndexOf returns the index of key, -1 when it is not in the list.
+func (this *SortedList[K, V]) IndexOf(key K) int {
+	this.list.locker.Lock()
+	defer this.list.locker.Unlock()
+	node, ok := this.nodes[key]
+	if !ok {
+		return -1
+	}
+	rank := 0
+	x := this.header
+	for i := this.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && (x.next[i] == node || this.before(x.next[i], node)) {
+			rank += x.span[i]
+			x = x.next[i]
+		}
+		if x == node {
+			return rank - 1
+		}
+	}
+	return -1
+}
+
+// Scan calls fn for the elements from <= value < to in order until fn returns
+// false. fn runs with the list locked and must not call back into it.
+func (this *SortedList[K, V]) Scan(from V, to V, fn func(key K, data V) bool) {
+	this.list.locker.Lock()
+	defer this.list.locker.Unlock()
+	x := this.header
+	for i := this.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && this.less(x.next[i].item.Data, from) {
+			x = x.next[i]
+		}
+	}
+	for x = x.next[0]; x != nil && this.less(x.item.Data, to); x = x.next[0] {
+		if !fn(x.item.Key, x.item.Data) {
+			return
+		}
+	}
+}
+
+func (this *SortedList[K, V]) Head() (key K, element V) {
+	return this.list.Head()
+}
+
+func (this *SortedList[K, V]) Tail() (key K, element V) {
+	return this.list.Tail()
+}
+
+func (this *SortedList[K, V]) Next(target K) (key K, element V) {
+	return this.list.Next(target)
+}
+
+func (this *SortedList[K, V]) Prev(target K) (key K, element V) {
+	return this.list.Prev(target)
+}
+
+// Keys returns every key in sorted order.
+func (this *SortedList[K, V]) Keys() []K {
+	this.list.locker.Lock()
+	defer this.list.locker.Unlock()
+	return this.list.keysNoLock()
+}
+
+func (this *SortedList[K, V]) Size() int {
+	this.list.locker.Lock()
+	defer this.list.locker.Unlock()
+	return this.length
+}
+
+func (this *SortedList[K, V]) Subscribe(buffer int, filter EventFilter[K, V]) *Subscription[K, V] {
+	return this.list.Subscribe(buffer, filter)
+}
+
+func (this *SortedList[K, V]) CreateEventListener(buffer int) (int, chan Event[K, V]) {
+	return this.list.CreateEventListener(buffer)
+}
+
+func (this *SortedList[K, V]) SetCodec(codec Codec) {
+	this.list.SetCodec(codec)
+}
+
+// insertNoLock puts temp into the skip list and links it after its skip list
+// predecessor.
+func (this *SortedList[K, V]) insertNoLock(temp *Component[K, V]) {
+	this.sequence++
+	node := &sortedNode[K, V]{item: temp, sequence: this.sequence}
+	update := make([]*sortedNode[K, V], sortedMaxLevel)
+	rank := make([]int, sortedMaxLevel)
+	x := this.header
+	for i := this.level - 1; i >= 0; i-- {
+		if i < this.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.next[i] != nil && this.before(x.next[i], node) {
+			rank[i] += x.span[i]
+			x = x.next[i]
+		}
+		update[i] = x
+	}
+	level := sortedLevel()
+	if level > this.level {
+		for i := this.level; i < level; i++ {
+			update[i] = this.header
+			this.header.span[i] = this.length
+		}
+		this.level = level
+	}
+	node.next = make([]*sortedNode[K, V], level)
+	node.span = make([]int, level)
+	for i := 0; i < level; i++ {
+		node.next[i] = update[i].next[i]
+		update[i].next[i] = node
+		node.span[i] = update[i].span[i] - (rank[0] - rank[i])
+		update[i].span[i] = rank[0] - rank[i] + 1
+	}
+	for i := level; i < this.level; i++ {
+		update[i].span[i]++
+	}
+	this.length++
+	this.nodes[temp.Key] = node
+
+	if update[0] == this.header {
+		this.list.linkFirstNoLock(temp)
+	} else {
+		this.list.linkAfterNoLock(temp, update[0].item)
+	}
+}
+
+// deleteNoLock takes node out of the skip list, the caller unlinks its item.
+func (this *SortedList[K, V]) deleteNoLock(node *sortedNode[K, V]) {
+	update := make([]*sortedNode[K, V], sortedMaxLevel)
+	x := this.header
+	for i := this.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && this.before(x.next[i], node) {
+			x = x.next[i]
+		}
+		update[i] = x
+	}
+	for i := 0; i < this.level; i++ {
+		if update[i].next[i] == node {
+			update[i].span[i] += node.span[i] - 1
+			update[i].next[i] = node.next[i]
+		} else {
+			update[i].span[i]--
+		}
+	}
+	for this.level > 1 && this.header.next[this.level-1] == nil {
+		this.level--
+	}
+	this.length--
+	delete(this.nodes, node.item.Key)
+}
+
+// atNoLock returns the node at rank, 1 is the first.
+func (this *SortedList[K, V]) atNoLock(rank int) *sortedNode[K, V] {
+	if rank < 1 || rank > this.length {
+		return nil
+	}
+	traversed := 0
+	x := this.header
+	for i := this.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && traversed+x.span[i] <= rank {
+			traversed += x.span[i]
+			x = x.next[i]
+		}
+		if traversed == rank {
+			return x
+		}
+	}
+	return nil
+}
+
+// before orders by less and equal values by insertion.
+func (this *SortedList[K, V]) before(left *sortedNode[K, V], right *sortedNode[K, V]) bool {
+	if this.less(left.item.Data, right.item.Data) {
+		return true
+	}
+	return !this.less(right.item.Data, left.item.Data) && left.sequence < right.sequence
+}
+
+func sortedLevel() int {
+	level := 1
+	for level < sortedMaxLevel && rand.Intn(4) == 0 {
+		level++
+	}
+	return level
+}
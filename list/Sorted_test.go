@@ -0,0 +1,156 @@
+package list
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// sortedModel is what a SortedList should hold, equal values in the order
+// they were added or last updated.
+type sortedModel struct {
+	keys   []string
+	values map[string]int
+}
+
+func (this *sortedModel) put(key string, value int) {
+	this.remove(key)
+	this.values[key] = value
+	this.keys = append(this.keys, key)
+	sort.SliceStable(this.keys, func(i, j int) bool {
+		return this.values[this.keys[i]] < this.values[this.keys[j]]
+	})
+}
+
+func (this *sortedModel) remove(key string) {
+	for index, item := range this.keys {
+		if item == key {
+			this.keys = append(this.keys[:index], this.keys[index+1:]...)
+			break
+		}
+	}
+	delete(this.values, key)
+}
+
+func checkSorted(t *testing.T, list *SortedList[string, int], model *sortedModel) {
+	t.Helper()
+	if got, want := fmt.Sprint(list.Keys()), fmt.Sprint(model.keys); got != want {
+		t.Fatal(got, want)
+	}
+	if list.Size() != len(model.keys) {
+		t.Fatal(list.Size(), len(model.keys))
+	}
+	for index, key := range model.keys {
+		if got, value, ok := list.At(index); !ok || got != key || value != model.values[key] {
+			t.Fatal(index, got, key)
+		}
+		if list.IndexOf(key) != index {
+			t.Fatal(key, list.IndexOf(key), index)
+		}
+	}
+}
+
+func TestSortedListOrder(t *testing.T) {
+	list := NewSorted[string, int](func(left int, right int) bool {
+		return left < right
+	})
+	model := &sortedModel{values: make(map[string]int)}
+	random := rand.New(rand.NewSource(1))
+	for step := 0; step < 2000; step++ {
+		key := fmt.Sprint(random.Intn(100))
+		value := random.Intn(20)
+		_, exist := model.values[key]
+		switch {
+		case !exist:
+			if err := list.Add(key, value); err != nil {
+				t.Fatal(err)
+			}
+			model.put(key, value)
+		case random.Intn(3) == 0:
+			if list.Remove(key) != model.values[key] {
+				t.Fatal("Remove returned the wrong value")
+			}
+			model.remove(key)
+		default:
+			if err := list.Update(key, value); err != nil {
+				t.Fatal(err)
+			}
+			model.put(key, value)
+		}
+		if step%100 == 0 {
+			checkSorted(t, list, model)
+		}
+	}
+	checkSorted(t, list, model)
+	if err := list.Add(model.keys[0], 1); err == nil {
+		t.Fatal("duplicate key was added")
+	}
+	if err := list.Update("missing", 1); err == nil {
+		t.Fatal("missing key was updated")
+	}
+}
+
+func TestSortedListStable(t *testing.T) {
+	list := NewSorted[string, int](func(left int, right int) bool {
+		return left < right
+	})
+	list.Add("a", 1)
+	list.Add("b", 0)
+	list.Add("c", 1)
+	list.Add("d", 1)
+	list.Update("a", 1)
+	if keys := fmt.Sprint(list.Keys()); keys != "[b c d a]" {
+		t.Fatal(keys)
+	}
+	if key, _ := list.Head(); key != "b" {
+		t.Fatal(key)
+	}
+	if key, _ := list.Next("c"); key != "d" {
+		t.Fatal(key)
+	}
+}
+
+func TestSortedListBounds(t *testing.T) {
+	list := NewSorted[string, int](func(left int, right int) bool {
+		return left < right
+	})
+	for _, value := range []int{5, 1, 3, 3, 9, 7} {
+		list.Add(fmt.Sprint("k", value, "-", list.Size()), value)
+	}
+	for _, index := range []int{-1, 6, 100} {
+		if _, _, ok := list.At(index); ok {
+			t.Fatal("At", index)
+		}
+	}
+	if list.IndexOf("missing") != -1 {
+		t.Fatal("IndexOf missing")
+	}
+
+	scan := func(from int, to int, limit int) string {
+		var values []int
+		list.Scan(from, to, func(key string, data int) bool {
+			values = append(values, data)
+			return len(values) < limit
+		})
+		return fmt.Sprint(values)
+	}
+	cases := []struct {
+		from, to, limit int
+		want            string
+	}{
+		{3, 7, 10, "[3 3 5]"},
+		{0, 100, 10, "[1 3 3 5 7 9]"},
+		{-5, 1, 10, "[]"},
+		{9, 9, 10, "[]"},
+		{9, 10, 10, "[9]"},
+		{7, 3, 10, "[]"},
+		{10, 20, 10, "[]"},
+		{1, 100, 2, "[1 3]"},
+	}
+	for _, test := range cases {
+		if got := scan(test.from, test.to, test.limit); got != test.want {
+			t.Fatal(test.from, test.to, got)
+		}
+	}
+}
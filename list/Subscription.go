@@ -0,0 +1,405 @@
+package list
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventFilter decides whether a subscriber receives event.
+type EventFilter[K comparable, V any] func(event Event[K, V]) bool
+
+// EventTypes accepts only the listed kinds of event. A BATCH is accepted as a
+// whole when it is listed or one of its changes is.
+func EventTypes[K comparable, V any](events ...EVENT) EventFilter[K, V] {
+	var filter EventFilter[K, V]
+	filter = func(event Event[K, V]) bool {
+		for _, kind := range events {
+			if event.Event == kind {
+				return true
+			}
+		}
+		return event.Event == BATCH && anyInBatch(event, filter)
+	}
+	return filter
+}
+
+// KeyPrefix accepts events whose key starts with prefix, a BATCH when one of
+// its changes does. Keys that are not strings are compared by their fmt.Sprint
+// form.
+func KeyPrefix[K comparable, V any](prefix string) EventFilter[K, V] {
+	return func(event Event[K, V]) bool {
+		if event.Event == BATCH {
+			return anyInBatch(event, KeyPrefix[K, V](prefix))
+		}
+		if event.Component == nil {
+			return false
+		}
+		if key, ok := any(event.Key).(string); ok {
+			return strings.HasPrefix(key, prefix)
+		}
+		return strings.HasPrefix(fmt.Sprint(event.Key), prefix)
+	}
+}
+
+func anyInBatch[K comparable, V any](event Event[K, V], filter EventFilter[K, V]) bool {
+	for _, change := range event.Batch {
+		if filter(change) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchAll accepts an event only when every filter does.
+func MatchAll[K comparable, V any](filters ...EventFilter[K, V]) EventFilter[K, V] {
+	return func(event Event[K, V]) bool {
+		for _, filter := range filters {
+			if filter != nil && !filter(event) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// DELIVERY is what a subscription does when its channel is full.
+//
+// Events are always handed over in the order the list applied them. BLOCK
+// keeps every event but stalls the list (it holds its lock) until the consumer
+// catches up or the timeout passes. The DROP policies never block and lose
+// events instead. COALESCE never blocks either, it parks overflowing events and
+// keeps only the latest pending event per key, so a slow consumer sees the final
+// state of each element rather than every step. Events that are not about a
+// single element, such as SORT, are parked as they are. At most SetParkLimit
+// events are parked, what overflows is dropped. A DROP_OLDEST subscription
+// without buffer drops the event it cannot hand over right away.
+type DELIVERY int
+
+// DefaultParkLimit is how many events a COALESCE subscription parks by default.
+const DefaultParkLimit = 4096
+
+const (
+	BLOCK DELIVERY = iota
+	DROP_NEWEST
+	DROP_OLDEST
+	COALESCE
+)
+
+// Subscription is one consumer of a list's change stream. Every subscriber has
+// its own channel, events are fanned out to all of them in the order the list
+// applied the changes.
+type Subscription[K comparable, V any] struct {
+	list      *List[K, V]
+	channel   chan Event[K, V]
+	filter    EventFilter[K, V]
+	owned     bool
+	policy    DELIVERY
+	timeout   time.Duration
+	done      chan struct{}
+	once      sync.Once
+	dropped   atomic.Uint64
+	coalesced atomic.Uint64
+
+	parkLocker sync.Mutex
+	parked     []parkedEvent[K, V]
+	live       int // parked events that are not stale
+	parkLimit  int
+	latest     map[K]int
+	pumping    bool
+	wake       chan struct{}
+	halt       chan struct{}
+	pump       sync.WaitGroup
+}
+
+// parkedEvent is stale once a newer event for its key was parked behind it.
+type parkedEvent[K comparable, V any] struct {
+	event Event[K, V]
+	stale bool
+}
+
+// Subscribe registers a new consumer with a channel of the given buffer. A nil
+// filter receives every event. Delivery starts as BLOCK without timeout.
+func (this *List[K, V]) Subscribe(buffer int, filter EventFilter[K, V]) *Subscription[K, V] {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	subscription := this.subscribeNoLock(make(chan Event[K, V], buffer), filter)
+	subscription.owned = true
+	return subscription
+}
+
+func (this *List[K, V]) subscribeNoLock(channel chan Event[K, V], filter EventFilter[K, V]) *Subscription[K, V] {
+	subscription := &Subscription[K, V]{
+		list:    this,
+		channel: channel,
+		filter:  filter,
+		done:    make(chan struct{}),
+	}
+	this.subscribers = append(this.subscribers, subscription)
+	return subscription
+}
+
+// Listener returns the subscription behind CreateEventListener or
+// SetEventListener, nil when neither was called.
+func (this *List[K, V]) Listener() *Subscription[K, V] {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return this.listener
+}
+
+func (this *List[K, V]) Subscribers() int {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return len(this.subscribers)
+}
+
+func (this *Subscription[K, V]) Events() <-chan Event[K, V] {
+	return this.channel
+}
+
+// SetDelivery changes what happens when the channel is full. timeout only
+// applies to BLOCK, zero waits as long as it takes. Leaving COALESCE hands the
+// parked events over under the new policy before any newer event.
+func (this *Subscription[K, V]) SetDelivery(policy DELIVERY, timeout time.Duration) {
+	this.list.locker.Lock()
+	defer this.list.locker.Unlock()
+	if policy == COALESCE && this.wake == nil {
+		this.wake = make(chan struct{}, 1)
+		this.halt = make(chan struct{})
+		this.latest = make(map[K]int)
+		this.pump.Add(1)
+		go this.pumpParked(this.wake, this.halt)
+	}
+	this.policy = policy
+	this.timeout = timeout
+	if policy != COALESCE && this.wake != nil {
+		close(this.halt)
+		this.pump.Wait()
+		this.parkLocker.Lock()
+		parked := this.parked
+		this.parked, this.live, this.latest = nil, 0, nil
+		this.wake, this.halt = nil, nil
+		this.parkLocker.Unlock()
+		for _, item := range parked {
+			if !item.stale {
+				this.send(item.event)
+			}
+		}
+	}
+}
+
+// Dropped is the number of events this subscription lost to its policy.
+func (this *Subscription[K, V]) Dropped() uint64 {
+	return this.dropped.Load()
+}
+
+// Coalesced is the number of events replaced by a newer event for the same key.
+func (this *Subscription[K, V]) Coalesced() uint64 {
+	return this.coalesced.Load()
+}
+
+// Unsubscribe stops delivery and closes the channel created by Subscribe.
+// Events still buffered in the channel can be drained afterwards.
+func (this *Subscription[K, V]) Unsubscribe() {
+	this.once.Do(func() {
+		close(this.done)
+		this.list.locker.Lock()
+		for i, subscriber := range this.list.subscribers {
+			if subscriber == this {
+				this.list.subscribers = append(this.list.subscribers[:i:i], this.list.subscribers[i+1:]...)
+				break
+			}
+		}
+		if this.list.listener == this {
+			this.list.listener = nil
+		}
+		this.list.locker.Unlock()
+		this.pump.Wait()
+		if this.owned {
+			close(this.channel)
+		}
+	})
+}
+
+// deliver runs under the list lock, which is what keeps events in order.
+func (this *Subscription[K, V]) deliver(event Event[K, V]) {
+	if this.filter != nil && !this.filter(event) {
+		return
+	}
+	this.send(event)
+}
+
+func (this *Subscription[K, V]) send(event Event[K, V]) {
+	switch this.policy {
+	case DROP_NEWEST:
+		select {
+		case this.channel <- event:
+		default:
+			this.dropped.Add(1)
+		}
+	case DROP_OLDEST:
+		for {
+			select {
+			case this.channel <- event:
+				return
+			default:
+			}
+			if cap(this.channel) == 0 {
+				// nothing is buffered that could make room
+				this.dropped.Add(1)
+				return
+			}
+			select {
+			case <-this.channel:
+				this.dropped.Add(1)
+			default:
+			}
+		}
+	case COALESCE:
+		this.park(event)
+	default:
+		if this.timeout <= 0 {
+			select {
+			case this.channel <- event:
+			case <-this.done:
+			}
+			return
+		}
+		timer := time.NewTimer(this.timeout)
+		defer timer.Stop()
+		select {
+		case this.channel <- event:
+		case <-this.done:
+		case <-timer.C:
+			this.dropped.Add(1)
+		}
+	}
+}
+
+// park hands event over right away when nothing is parked, else parks it. A
+// newer event for a parked key makes the parked one stale and goes to the end,
+// so events still leave in the order the list applied them.
+func (this *Subscription[K, V]) park(event Event[K, V]) {
+	this.parkLocker.Lock()
+	if this.live == 0 && !this.pumping {
+		select {
+		case this.channel <- event:
+			this.parkLocker.Unlock()
+			return
+		default:
+		}
+	}
+	single := event.Component != nil && elementEvent(event.Event)
+	if index, ok := this.latest[event.Key]; single && ok {
+		this.parked[index].stale = true
+		this.live--
+		this.coalesced.Add(1)
+	} else if this.live >= this.parkLimitNoLock() {
+		this.dropped.Add(1)
+		this.parkLocker.Unlock()
+		return
+	}
+	if len(this.parked) >= 2*this.live+16 {
+		this.compactNoLock()
+	}
+	if single {
+		this.latest[event.Key] = len(this.parked)
+	}
+	this.parked = append(this.parked, parkedEvent[K, V]{event: event})
+	this.live++
+	this.parkLocker.Unlock()
+	select {
+	case this.wake <- struct{}{}:
+	default:
+	}
+}
+
+// compactNoLock drops the stale parked events.
+func (this *Subscription[K, V]) compactNoLock() {
+	parked := make([]parkedEvent[K, V], 0, this.live)
+	for _, item := range this.parked {
+		if item.stale {
+			continue
+		}
+		if item.event.Component != nil && elementEvent(item.event.Event) {
+			this.latest[item.event.Key] = len(parked)
+		}
+		parked = append(parked, item)
+	}
+	this.parked = parked
+}
+
+// SetParkLimit caps how many events a COALESCE subscription parks, 0 or less
+// means DefaultParkLimit. Events for a key that is not parked yet, and those
+// that are not about a single element, are dropped once the limit is reached.
+func (this *Subscription[K, V]) SetParkLimit(limit int) {
+	this.parkLocker.Lock()
+	defer this.parkLocker.Unlock()
+	this.parkLimit = limit
+}
+
+func (this *Subscription[K, V]) parkLimitNoLock() int {
+	if this.parkLimit <= 0 {
+		return DefaultParkLimit
+	}
+	return this.parkLimit
+}
+
+// pumpParked moves parked events into the channel for COALESCE subscriptions.
+// When halt closes it parks again what it had not handed over yet.
+func (this *Subscription[K, V]) pumpParked(wake chan struct{}, halt chan struct{}) {
+	defer this.pump.Done()
+	for {
+		select {
+		case <-this.done:
+			return
+		case <-halt:
+			return
+		case <-wake:
+		}
+		this.parkLocker.Lock()
+		batch := this.parked
+		this.parked, this.live = nil, 0
+		this.pumping = true
+		for key := range this.latest {
+			delete(this.latest, key)
+		}
+		this.parkLocker.Unlock()
+		for index, item := range batch {
+			if item.stale {
+				continue
+			}
+			select {
+			case this.channel <- item.event:
+				continue
+			case <-this.done:
+				return
+			case <-halt:
+			}
+			this.parkLocker.Lock()
+			this.parked = append(batch[index:len(batch):len(batch)], this.parked...)
+			this.live = 0
+			for _, rest := range this.parked {
+				if !rest.stale {
+					this.live++
+				}
+			}
+			this.pumping = false
+			this.parkLocker.Unlock()
+			return
+		}
+		this.parkLocker.Lock()
+		this.pumping = false
+		this.parkLocker.Unlock()
+	}
+}
+
+func elementEvent(event EVENT) bool {
+	switch event {
+	case ADD, UPDATE, DELETE, EVICT, EXPIRE:
+		return true
+	}
+	return false
+}
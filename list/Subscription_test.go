@@ -0,0 +1,204 @@
+package list
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDropOldestUnbuffered(t *testing.T) {
+	list := New[string, int]()
+	subscription := list.Subscribe(0, nil)
+	defer subscription.Unsubscribe()
+	subscription.SetDelivery(DROP_OLDEST, 0)
+	done := make(chan struct{})
+	go func() {
+		list.AddLast("a", 1)
+		list.AddLast("b", 2)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("AddLast blocked on an unbuffered DROP_OLDEST subscription")
+	}
+	if subscription.Dropped() != 2 {
+		t.Fatal(subscription.Dropped())
+	}
+}
+
+func TestCoalesceParkLimit(t *testing.T) {
+	list := New[string, int]()
+	subscription := list.Subscribe(0, nil)
+	defer subscription.Unsubscribe()
+	subscription.SetDelivery(COALESCE, 0)
+	subscription.SetParkLimit(3)
+	for i := 0; i < 10; i++ {
+		list.AddLast(fmt.Sprint(i), i)
+	}
+	subscription.parkLocker.Lock()
+	parked := subscription.live
+	subscription.parkLocker.Unlock()
+	if parked > 3 || subscription.Dropped() < 6 {
+		t.Fatal(parked, subscription.Dropped())
+	}
+}
+
+// receive reads count events or fails after a second.
+func receive(t *testing.T, subscription *Subscription[string, int], count int) []Event[string, int] {
+	t.Helper()
+	var events []Event[string, int]
+	for len(events) < count {
+		select {
+		case event := <-subscription.Events():
+			events = append(events, event)
+		case <-time.After(time.Second):
+			t.Fatal("received", len(events), "of", count)
+		}
+	}
+	return events
+}
+
+func checkOrder(t *testing.T, events []Event[string, int]) {
+	t.Helper()
+	for index := 1; index < len(events); index++ {
+		if events[index].Sequence <= events[index-1].Sequence {
+			t.Fatal("out of order", events[index-1].Sequence, events[index].Sequence)
+		}
+	}
+}
+
+func TestCoalesceKeepsOrder(t *testing.T) {
+	list := New[string, int]()
+	subscription := list.Subscribe(0, nil)
+	defer subscription.Unsubscribe()
+	subscription.SetDelivery(COALESCE, 0)
+	for _, key := range []string{"a", "b", "c"} {
+		list.AddLast(key, 0)
+	}
+	for i := 1; i <= 50; i++ {
+		list.Update("a", i)
+		list.Update("b", i)
+	}
+	list.Update("c", 1)
+	// the pump may hold one event of each of a, b and c apart from the rest
+	events := receive(t, subscription, 3)
+	for len(events) == 0 || events[len(events)-1].Key != "c" || events[len(events)-1].Data != 1 {
+		events = append(events, receive(t, subscription, 1)...)
+	}
+	checkOrder(t, events)
+	if subscription.Coalesced() == 0 {
+		t.Fatal("nothing was coalesced")
+	}
+}
+
+func TestLeaveCoalesce(t *testing.T) {
+	list := New[string, int]()
+	subscription := list.Subscribe(0, nil)
+	defer subscription.Unsubscribe()
+	subscription.SetDelivery(COALESCE, 0)
+	for index, key := range []string{"a", "b", "c"} {
+		list.AddLast(key, index)
+	}
+	received := make(chan Event[string, int], 4)
+	go func() {
+		for index := 0; index < 4; index++ {
+			received <- <-subscription.Events()
+		}
+	}()
+	subscription.SetDelivery(BLOCK, 0)
+	list.AddLast("d", 3)
+	var events []Event[string, int]
+	for len(events) < 4 {
+		select {
+		case event := <-received:
+			events = append(events, event)
+		case <-time.After(time.Second):
+			t.Fatal("received", len(events))
+		}
+	}
+	checkOrder(t, events)
+	if events[3].Key != "d" {
+		t.Fatal(events[3].Key)
+	}
+}
+
+func kindsOf(t *testing.T, subscription *Subscription[string, int], count int) string {
+	t.Helper()
+	var kinds []string
+	for _, event := range receive(t, subscription, count) {
+		kinds = append(kinds, fmt.Sprint(event.Event, ":", event.Key))
+	}
+	if len(subscription.Events()) != 0 {
+		t.Fatal("more events than expected")
+	}
+	return fmt.Sprint(kinds)
+}
+
+func TestSubscribeFanOut(t *testing.T) {
+	list := New[string, int]()
+	size, legacy := list.CreateEventListener(8)
+	all := list.Subscribe(8, nil)
+	deletes := list.Subscribe(8, EventTypes[string, int](DELETE))
+	prefixed := list.Subscribe(8, MatchAll(KeyPrefix[string, int]("a"), EventTypes[string, int](ADD, BATCH)))
+	if size != 8 || list.Subscribers() != 4 || list.Listener() == nil {
+		t.Fatal(size, list.Subscribers())
+	}
+	if list.SetEventListener(make(chan Event[string, int])) {
+		t.Fatal("second listener was set")
+	}
+
+	list.AddLast("a1", 1)
+	list.AddLast("b1", 2)
+	list.Remove("a1")
+	list.Batch(func(tx *ListTx[string, int]) error {
+		return tx.AddLast("a2", 3)
+	})
+
+	want := fmt.Sprint([]string{
+		fmt.Sprint(ADD, ":a1"), fmt.Sprint(ADD, ":b1"), fmt.Sprint(DELETE, ":a1"), fmt.Sprint(BATCH, ":a2"),
+	})
+	if got := kindsOf(t, all, 4); got != want {
+		t.Fatal(got)
+	}
+	var legacyEvents []string
+	for len(legacy) > 0 {
+		event := <-legacy
+		legacyEvents = append(legacyEvents, fmt.Sprint(event.Event, ":", event.Key))
+	}
+	if fmt.Sprint(legacyEvents) != want {
+		t.Fatal(legacyEvents)
+	}
+	if got := kindsOf(t, deletes, 1); got != fmt.Sprint([]string{fmt.Sprint(DELETE, ":a1")}) {
+		t.Fatal(got)
+	}
+	if got := kindsOf(t, prefixed, 2); got != fmt.Sprint([]string{fmt.Sprint(ADD, ":a1"), fmt.Sprint(BATCH, ":a2")}) {
+		t.Fatal(got)
+	}
+
+	// Unsubscribe closes a channel made by Subscribe, not one the listener was given
+	all.Unsubscribe()
+	all.Unsubscribe()
+	if _, open := <-all.Events(); open {
+		t.Fatal("channel is still open")
+	}
+	list.Listener().Unsubscribe()
+	if list.Listener() != nil || list.Subscribers() != 2 {
+		t.Fatal(list.Subscribers())
+	}
+	list.AddLast("c", 4)
+	select {
+	case event := <-legacy:
+		t.Fatal("unsubscribed listener got", event)
+	default:
+	}
+	if !list.SetEventListener(legacy) {
+		t.Fatal("listener could not be set again")
+	}
+	list.AddLast("d", 5)
+	if event := <-legacy; event.Key != "d" {
+		t.Fatal(event.Key)
+	}
+	deletes.Unsubscribe()
+	prefixed.Unsubscribe()
+}
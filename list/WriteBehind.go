@@ -0,0 +1,158 @@
+package list
+
+import (
+	"time"
+)
+
+// SetWriteBehind stops the list from writing to storage while it is locked.
+// Writes are queued instead, merged per key like the repair queue, and sent
+// by a background goroutine once batch writes are queued or every interval,
+// whichever comes first. A value <= 0 disables that trigger, both <= 0 turns
+// write-behind off again after flushing what is queued.
+//
+// Storage errors can no longer roll a change back: the failed writes stay
+// queued for the next flush and are given to the storage error handler.
+// Flush and Close send everything queued before they return.
+func (this *List[K, V]) SetWriteBehind(batch int, interval time.Duration) error {
+	this.locker.Lock()
+	this.behindBatch = batch
+	if batch <= 0 && interval <= 0 {
+		this.writeBehind = false
+		this.locker.Unlock()
+		return this.flushAll()
+	}
+	this.writeBehind = true
+	if !this.flushing {
+		this.flushing = true
+		this.flushSignal = make(chan struct{}, 1)
+		signal := this.flushSignal
+		this.startWorkerNoLock(func(stop <-chan struct{}) {
+			this.flushEvery(interval, signal, stop)
+		})
+	}
+	this.locker.Unlock()
+	return nil
+}
+
+// Flush sends the queued writes to storage without holding the list lock, as
+// one Batch when storage is a BatchStorage, followed by the element order
+// when moves changed it. On an error the writes that were not sent stay
+// queued.
+func (this *List[K, V]) Flush() error {
+	this.flushLocker.Lock()
+	defer this.flushLocker.Unlock()
+	this.locker.Lock()
+	batch := this.behind
+	this.behind = writeQueue[K]{}
+	this.inflight = batch
+	storage := this.storage
+	if batch.len() == 0 {
+		// moves may still owe storage the order
+		this.reorderNoLock()
+		this.locker.Unlock()
+		return nil
+	}
+	this.locker.Unlock()
+
+	var failed StorageWrite[K]
+	var err error
+	if batcher, ok := batchStorage(storage); ok {
+		failed.Op = "batch"
+		if err = batcher.Batch(batch.list()); err == nil {
+			batch = writeQueue[K]{}
+		}
+	} else {
+		err = batch.drain(func(write StorageWrite[K]) error {
+			failed = write
+			return sendWrite(storage, write)
+		})
+	}
+
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	this.inflight = writeQueue[K]{}
+	if err == nil {
+		this.reorderNoLock()
+		return nil
+	}
+	// the unsent writes are older than whatever was queued meanwhile
+	newer := this.behind
+	this.behind = batch
+	newer.drain(func(write StorageWrite[K]) error {
+		this.behind.put(write)
+		return nil
+	})
+	failure := &StorageError[K]{Op: failed.Op, Key: failed.Key, Err: err}
+	this.reportNoLock(failure)
+	return failure
+}
+
+// Unflushed returns how many writes wait for Flush.
+func (this *List[K, V]) Unflushed() int {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return this.behind.len()
+}
+
+// flushAll flushes until nothing is queued, writes that arrive during a flush
+// are queued behind it.
+func (this *List[K, V]) flushAll() error {
+	for {
+		if err := this.Flush(); err != nil {
+			return err
+		}
+		this.locker.Lock()
+		empty := this.behind.len() == 0
+		this.locker.Unlock()
+		if empty {
+			return nil
+		}
+	}
+}
+
+func (this *List[K, V]) flushEvery(interval time.Duration, signal chan struct{}, stop <-chan struct{}) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-stop:
+			return
+		case <-tick:
+			this.Flush()
+		case <-signal:
+			this.Flush()
+		}
+	}
+}
+
+// behindNoLock queues write when write-behind is on or an older write of the
+// same key is still queued or being flushed.
+func (this *List[K, V]) behindNoLock(write StorageWrite[K]) bool {
+	if !this.writeBehind && !this.behind.pending(write.Key) && !this.inflight.pending(write.Key) {
+		return false
+	}
+	this.behind.put(write)
+	if this.behindBatch > 0 && this.behind.len() >= this.behindBatch {
+		select {
+		case this.flushSignal <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
+
+// unflushedNoLock returns the newest write of key that storage has not seen
+// yet, queued for a flush or for Repair. Reads have to trust it over storage.
+func (this *List[K, V]) unflushedNoLock(key K) (StorageWrite[K], bool) {
+	if write, ok := this.repairs.latest(key); ok {
+		return write, true
+	}
+	if write, ok := this.behind.latest(key); ok {
+		return write, true
+	}
+	return this.inflight.latest(key)
+}
@@ -0,0 +1,91 @@
+package list
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// eventually polls until check holds or a second has passed.
+func eventually(t *testing.T, check func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !check(); {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not reached")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWriteBehindBatchTrigger(t *testing.T) {
+	storage := newTestStorage()
+	list := NewWithCheckedStorage[string, int](storage)
+	defer list.Close()
+	list.SetWriteBehind(3, 0)
+	list.AddLast("a", 1)
+	list.AddLast("b", 2)
+	if list.Unflushed() != 2 || len(storage.data) != 0 {
+		t.Fatal(list.Unflushed(), len(storage.data))
+	}
+	list.AddLast("c", 3)
+	eventually(t, func() bool {
+		_, ok := storage.get("c")
+		return ok && list.Unflushed() == 0
+	})
+}
+
+func TestWriteBehindIntervalTrigger(t *testing.T) {
+	storage := newTestStorage()
+	list := NewWithCheckedStorage[string, int](storage)
+	defer list.Close()
+	list.SetWriteBehind(0, 5*time.Millisecond)
+	list.AddLast("a", 1)
+	eventually(t, func() bool {
+		data, ok := storage.get("a")
+		return ok && data == "1"
+	})
+}
+
+func TestWriteBehindRemoveStoredKey(t *testing.T) {
+	storage := newTestStorage()
+	storage.data["a"] = []byte("1")
+	list := NewWithCheckedStorage[string, int](storage)
+	defer list.Close()
+	list.SetWriteBehind(0, time.Hour)
+	if list.Find("a") != 1 {
+		t.Fatal("a was not loaded")
+	}
+	list.Update("a", 2)
+	list.Remove("a")
+	// the queued delete hides the stored value until it is flushed
+	if list.Find("a") != 0 {
+		t.Fatal("a came back before the flush")
+	}
+	if err := list.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := storage.get("a"); ok || list.Find("a") != 0 {
+		t.Fatal("a is still stored")
+	}
+}
+
+func TestWriteBehindCloseDrains(t *testing.T) {
+	storage := newTestStorage()
+	list := NewWithCheckedStorage[string, int](storage)
+	list.SetWriteBehind(0, time.Hour)
+	for i := 0; i < 10; i++ {
+		list.AddLast(fmt.Sprint(i), i)
+	}
+	list.Remove("3")
+	if err := list.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if list.Unflushed() != 0 || len(storage.data) != 9 {
+		t.Fatal(list.Unflushed(), len(storage.data))
+	}
+	// write-behind is off after Close
+	list.AddLast("x", 1)
+	if _, ok := storage.get("x"); !ok {
+		t.Fatal("x was queued after Close")
+	}
+}